@@ -12,8 +12,15 @@ func main() {
 	if err := bootstrap.InitAll(); err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
+	if err := bootstrap.InitIdentityProviders(); err != nil {
+		log.Fatalf("Failed to initialize identity providers: %v", err)
+	}
 
 	r := gin.Default()
+	// 登录限流和审计按 ClientIP 计算，只接受来自网关的 X-Forwarded-For
+	if err := r.SetTrustedProxies(bootstrap.TrustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -26,6 +33,9 @@ func main() {
 		c.Next()
 	})
 
+	r.GET("/.well-known/jwks.json", auth.JWKS)
+	r.GET("/.well-known/openid-configuration", auth.OpenIDConfiguration)
+
 	api := r.Group("/api/v1/auth")
 	{
 		api.POST("/register", func(c *gin.Context) {
@@ -36,9 +46,54 @@ func main() {
 			log.Printf("Login request from: %s", c.ClientIP())
 			auth.Login(c)
 		})
+		api.POST("/login/2fa", func(c *gin.Context) {
+			log.Printf("Login 2FA request from: %s", c.ClientIP())
+			auth.LoginMFA(c)
+		})
 		api.POST("/token/refresh", auth.RefreshToken)
+		api.POST("/email/verify", auth.VerifyEmail)
+		api.POST("/email/resend", auth.ResendVerification)
+		api.POST("/password/forgot", func(c *gin.Context) {
+			log.Printf("Forgot password request from: %s", c.ClientIP())
+			auth.ForgotPassword(c)
+		})
+		api.POST("/password/reset", auth.ResetPassword)
+		api.POST("/logout", auth.RequireUser(), auth.Logout)
 		api.GET("/oauth2/authorize", auth.Authorize)
+		api.POST("/oauth2/authorize", auth.Approve)
 		api.POST("/oauth2/token", auth.Token)
+		api.POST("/oauth2/revoke", auth.Revoke)
+		api.POST("/oauth2/device_authorization", auth.DeviceAuthorization)
+		api.GET("/oauth2/device", auth.DeviceVerification)
+		api.POST("/oauth2/device", auth.DeviceVerificationSubmit)
+		api.POST("/oauth2/device/approve", auth.RequireUser(), auth.ApproveDevice)
+		api.GET("/oauth2/userinfo", auth.UserInfo)
+		api.POST("/oauth2/userinfo", auth.UserInfo)
+
+		mfa := api.Group("/2fa")
+		mfa.Use(auth.RequireUser())
+		{
+			mfa.GET("", auth.GetMFAStatus)
+			mfa.POST("/totp/setup", auth.SetupTOTP)
+			mfa.POST("/totp/enable", auth.EnableTOTP)
+			mfa.POST("/totp/disable", auth.DisableTOTP)
+			mfa.POST("/recovery-codes", auth.RegenerateRecoveryCodes)
+		}
+
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(auth.RequireUser())
+		{
+			apiKeys.POST("", auth.CreateAPIKey)
+			apiKeys.GET("", auth.GetAPIKeys)
+			apiKeys.DELETE("/:id", auth.RevokeAPIKey)
+		}
+
+		clients := api.Group("/oauth2/clients")
+		clients.Use(auth.RequireUser())
+		{
+			clients.POST("", auth.RegisterClient)
+			clients.GET("", auth.GetClients)
+		}
 	}
 
 	log.Println("Auth Service starting on :8081")
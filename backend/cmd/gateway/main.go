@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 
 	"github.com/cyperlo/im/internal/friend"
 	"github.com/cyperlo/im/internal/gateway"
 	"github.com/cyperlo/im/internal/group"
 	"github.com/cyperlo/im/internal/message"
 	"github.com/cyperlo/im/pkg/bootstrap"
+	"github.com/cyperlo/im/pkg/revocation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +19,20 @@ func main() {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
 
+	if err := bootstrap.InitWebSocket(); err != nil {
+		log.Fatalf("Failed to initialize websocket: %v", err)
+	}
+
+	gateway.InitAuthService(os.Getenv("AUTH_SERVICE_URL"))
+	gateway.InitAdmins(os.Getenv("ADMIN_USER_IDS"))
+	revocation.Subscribe(context.Background(), gateway.HandleRevocation)
+	go gateway.SweepRevokedSessions(context.Background())
+
 	r := gin.Default()
+	// 转发给认证服务的客户端 IP 由 ClientIP 决定，只接受前面负载均衡的 X-Forwarded-For
+	if err := r.SetTrustedProxies(bootstrap.TrustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	r.Use(func(c *gin.Context) {
 		log.Printf("[%s] %s %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
@@ -35,14 +51,25 @@ func main() {
 	api := r.Group("/api/v1")
 	{
 		api.POST("/login", gateway.Login)
+		api.POST("/login/2fa", gateway.LoginMFA)
+
+		// 以下接口同时接受应用 token（client_credentials）
+		api.POST("/messages", gateway.AuthMiddleware("messages:send"), func(c *gin.Context) {
+			log.Printf("SendMessage called")
+			gateway.SendMessage(c)
+		})
+		api.GET("/conversations/:id/messages", gateway.AuthMiddleware("conversations:read"), func(c *gin.Context) {
+			log.Printf("GetConversationHistory called")
+			message.GetConversationHistory(c)
+		})
+		api.GET("/conversations/:id/sync", gateway.AuthMiddleware("conversations:read"), func(c *gin.Context) {
+			log.Printf("SyncConversation called")
+			message.SyncConversation(c)
+		})
 
 		protected := api.Group("")
 		protected.Use(gateway.AuthMiddleware())
 		{
-			protected.POST("/messages", func(c *gin.Context) {
-				log.Printf("SendMessage called")
-				gateway.SendMessage(c)
-			})
 			protected.POST("/friends", func(c *gin.Context) {
 				log.Printf("AddFriend called")
 				friend.AddFriend(c)
@@ -87,6 +114,27 @@ func main() {
 				log.Printf("DeleteFriend called")
 				friend.DeleteFriend(c)
 			})
+			protected.GET("/auth/sessions", func(c *gin.Context) {
+				log.Printf("GetSessions called")
+				gateway.GetSessions(c)
+			})
+			protected.DELETE("/auth/sessions/:id", func(c *gin.Context) {
+				log.Printf("RevokeSession called")
+				gateway.RevokeSession(c)
+			})
+		}
+
+		admin := api.Group("/admin")
+		admin.Use(gateway.AuthMiddleware(), gateway.RequireAdmin())
+		{
+			admin.GET("/connections", func(c *gin.Context) {
+				log.Printf("GetConnections called")
+				gateway.GetConnections(c)
+			})
+			admin.GET("/ws-stats", func(c *gin.Context) {
+				log.Printf("GetSendStats called")
+				gateway.GetSendStats(c)
+			})
 		}
 	}
 
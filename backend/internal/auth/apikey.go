@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyPrefix       = "imk_"
+	apiKeyDisplayChars = 12
+	maxAPIKeysPerUser  = 50
+
+	// last_used_at 精确到分钟即可，避免每个请求都写库
+	apiKeyTouchInterval = time.Minute
+)
+
+// API key 可申请的 scope，与应用 token 一样只能访问声明了 scope 的接口
+var apiKeyScopes = appScopes
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResult struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKey 创建 API key，明文只在这次响应中返回
+func CreateAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的 scope: " + scope})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "过期时间必须晚于当前时间"})
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= maxAPIKeysPerUser {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key 数量已达上限"})
+		return
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 API key 失败"})
+		return
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    rawKey[:apiKeyDisplayChars],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    strings.Join(req.Scopes, " "),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := database.DB.Create(key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建 API key 失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     rawKey,
+		"api_key": toAPIKeyResult(key),
+	})
+}
+
+// GetAPIKeys 列出当前用户未吊销的 API key
+func GetAPIKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var keys []models.APIKey
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取 API key 列表失败"})
+		return
+	}
+
+	result := make([]APIKeyResult, 0, len(keys))
+	for i := range keys {
+		result = append(result, toAPIKeyResult(&keys[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": result})
+}
+
+// RevokeAPIKey 吊销 API key，立即生效
+func RevokeAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result := database.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销 API key 失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key 不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key 已吊销"})
+}
+
+// IsAPIKey 根据前缀区分 API key 和 JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// ValidateAPIKey 校验 API key 是否存在、未吊销且未过期，并记录最近使用时间和 IP
+func ValidateAPIKey(ctx context.Context, rawKey, ip string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := database.DB.WithContext(ctx).
+		Where("key_hash = ? AND revoked_at IS NULL", hashToken(rawKey)).
+		First(&key).Error; err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchInterval || key.LastUsedIP != ip {
+		now := time.Now()
+		if err := database.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			log.Printf("Failed to update api key %s last used: %v", key.ID, err)
+		}
+		key.LastUsedAt = &now
+		key.LastUsedIP = ip
+	}
+
+	return &key, nil
+}
+
+// APIKeyHasScopes 判断 API key 是否包含全部指定的 scope
+func APIKeyHasScopes(key *models.APIKey, scopes ...string) bool {
+	granted := strings.Fields(key.Scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func toAPIKeyResult(key *models.APIKey) APIKeyResult {
+	return APIKeyResult{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// createAPIKey 以 userID 的身份调用创建接口
+func createAPIKey(t *testing.T, userID, body string) (int, string, APIKeyResult) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api-keys", func(c *gin.Context) { c.Set("user_id", userID) }, CreateAPIKey)
+
+	req := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Key    string       `json:"key"`
+		APIKey APIKeyResult `json:"api_key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Key, resp.APIKey
+}
+
+func TestCreateAPIKeyStoresHash(t *testing.T) {
+	setupTokenTest(t)
+
+	status, rawKey, result := createAPIKey(t, "user-1", `{"name":"ci","scopes":["messages:send"]}`)
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d", status)
+	}
+	if !IsAPIKey(rawKey) || result.Prefix != rawKey[:apiKeyDisplayChars] {
+		t.Fatalf("key = %q, prefix = %q", rawKey, result.Prefix)
+	}
+
+	var stored models.APIKey
+	if err := database.DB.Where("id = ?", result.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 数据库里只有哈希和用于展示的前缀
+	if stored.KeyHash != hashToken(rawKey) || strings.Contains(stored.KeyHash, rawKey[len(apiKeyPrefix):]) {
+		t.Errorf("stored key hash = %q", stored.KeyHash)
+	}
+	if stored.UserID != "user-1" || stored.Scopes != "messages:send" {
+		t.Errorf("stored key = %+v", stored)
+	}
+
+	// 列表接口不再返回明文
+	data, _ := json.Marshal(toAPIKeyResult(&stored))
+	if strings.Contains(string(data), rawKey) {
+		t.Errorf("api key result leaks the key: %s", data)
+	}
+}
+
+func TestCreateAPIKeyRejects(t *testing.T) {
+	setupTokenTest(t)
+
+	for name, body := range map[string]string{
+		"user scope":       `{"name":"ci","scopes":["openid"]}`,
+		"no scopes":        `{"name":"ci","scopes":[]}`,
+		"expired":          `{"name":"ci","scopes":["messages:send"],"expires_at":"2000-01-01T00:00:00Z"}`,
+		"missing name":     `{"scopes":["messages:send"]}`,
+		"name too long":    `{"name":"` + strings.Repeat("x", 101) + `","scopes":["messages:send"]}`,
+		"malformed scopes": `{"name":"ci","scopes":"messages:send"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if status, rawKey, _ := createAPIKey(t, "user-1", body); status != http.StatusBadRequest || rawKey != "" {
+				t.Fatalf("status = %d, key = %q", status, rawKey)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	setupTokenTest(t)
+	ctx := context.Background()
+
+	_, rawKey, result := createAPIKey(t, "user-1", `{"name":"ci","scopes":["messages:send","conversations:read"]}`)
+
+	key, err := ValidateAPIKey(ctx, rawKey, "198.51.100.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != result.ID || key.UserID != "user-1" || key.LastUsedIP != "198.51.100.7" || key.LastUsedAt == nil {
+		t.Errorf("ValidateAPIKey() = %+v", key)
+	}
+	if !APIKeyHasScopes(key, "messages:send") || !APIKeyHasScopes(key, "messages:send", "conversations:read") {
+		t.Error("key is missing a granted scope")
+	}
+	if APIKeyHasScopes(key, "messages:send", "openid") {
+		t.Error("key has a scope it was not granted")
+	}
+
+	for name, raw := range map[string]string{
+		"unknown key":  apiKeyPrefix + "unknown",
+		"prefix only":  result.Prefix,
+		"hash as key":  hashToken(rawKey),
+		"modified key": rawKey[:len(rawKey)-1] + "x",
+	} {
+		if _, err := ValidateAPIKey(ctx, raw, ""); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%s: ValidateAPIKey() = %v, want ErrInvalidAPIKey", name, err)
+		}
+	}
+
+	database.DB.Model(&models.APIKey{}).Where("id = ?", result.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := ValidateAPIKey(ctx, rawKey, ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expired key = %v, want ErrInvalidAPIKey", err)
+	}
+
+	database.DB.Model(&models.APIKey{}).Where("id = ?", result.ID).Updates(map[string]interface{}{"expires_at": nil, "revoked_at": time.Now()})
+	if _, err := ValidateAPIKey(ctx, rawKey, ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("revoked key = %v, want ErrInvalidAPIKey", err)
+	}
+}
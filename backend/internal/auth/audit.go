@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"log"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/google/uuid"
+)
+
+const (
+	AuditLoginFailed    = "login_failed"
+	AuditLoginThrottled = "login_throttled"
+	AuditAccountLocked  = "account_locked"
+)
+
+// RecordAuditEvent 写入审计记录，写库失败只记日志，不影响请求本身
+func RecordAuditEvent(event models.AuditEvent) {
+	event.ID = uuid.New().String()
+	event.Username = truncate(event.Username, 50)
+	event.UserAgent = truncate(event.UserAgent, 255)
+	event.Detail = truncate(event.Detail, 255)
+	event.CreatedAt = time.Now()
+
+	log.Printf("Audit: type=%s user=%s(%s) ip=%s detail=%s",
+		event.Type, event.Username, event.UserID, event.IP, event.Detail)
+
+	if err := database.DB.Create(&event).Error; err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Type, err)
+	}
+}
@@ -0,0 +1,436 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	deviceCodeTTL       = 10 * time.Minute
+	devicePollInterval  = 5 // 秒
+
+	deviceCodePending  = "pending"
+	deviceCodeApproved = "approved"
+	deviceCodeDenied   = "denied"
+)
+
+// user_code 只用辅音字母，避免拼出单词，也不会和数字混淆（RFC 8628 6.1）
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+var ErrInvalidUserCode = errors.New("invalid user code")
+
+type DeviceApprovalRequest struct {
+	UserCode string `json:"user_code" form:"user_code" binding:"required"`
+	Action   string `json:"action" form:"action"`
+	Username string `form:"username"`
+	Password string `form:"password"`
+	OTP      string `form:"otp"`
+}
+
+var deviceTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>设备登录</title>
+</head>
+<body>
+{{if .Done}}<h2>{{.Done}}</h2>
+{{else}}<h2>{{if .ClientName}}{{.ClientName}} 请求访问你的 IM 账号{{else}}输入设备上显示的代码{{end}}</h2>
+{{if .Scopes}}<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>{{end}}
+{{if .Error}}<p style="color:red">{{.Error}}</p>{{end}}
+<form method="post">
+<p><input name="user_code" placeholder="设备代码" value="{{.UserCode}}" autocomplete="off"></p>
+<p><input name="username" placeholder="用户名" value="{{.Username}}" autocomplete="username"></p>
+<p><input name="password" type="password" placeholder="密码" autocomplete="current-password"></p>
+<p><input name="otp" placeholder="两步验证码或恢复码（已开启时填写）" autocomplete="one-time-code"></p>
+<button type="submit" name="action" value="approve">授权该设备</button>
+<button type="submit" name="action" value="deny">拒绝</button>
+</form>
+{{end}}</body>
+</html>`))
+
+// DeviceAuthorization RFC 8628 设备授权端点，返回 device_code 和供用户输入的 user_code
+func DeviceAuthorization(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	client, oerr := authenticateClient(c)
+	if oerr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, *oerr)
+		return
+	}
+
+	scope := c.PostForm("scope")
+	if scope == "" {
+		scope = client.Scopes
+	}
+	clientScopes := strings.Fields(client.Scopes)
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(clientScopes, s) {
+			writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_scope", "scope " + s + " is not allowed for this client"})
+			return
+		}
+	}
+
+	deviceCode, err := generateOpaqueToken()
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue device code"})
+		return
+	}
+
+	record := &models.DeviceCode{
+		DeviceCodeHash: hashToken(deviceCode),
+		ClientID:       client.ID,
+		Scope:          strings.Join(strings.Fields(scope), " "),
+		Status:         deviceCodePending,
+		Interval:       devicePollInterval,
+		ExpiresAt:      time.Now().Add(deviceCodeTTL),
+		CreatedAt:      time.Now(),
+	}
+
+	// user_code 空间较小，唯一索引冲突时重新生成
+	for attempt := 0; ; attempt++ {
+		record.UserCode, err = generateUserCode()
+		if err == nil {
+			err = database.DB.Create(record).Error
+		}
+		if err == nil || attempt >= 3 {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to create device code: %v", err)
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue device code"})
+		return
+	}
+
+	verificationURI := jwt.Issuer() + "/api/v1/auth/oauth2/device"
+	userCode := formatUserCode(record.UserCode)
+	c.JSON(http.StatusOK, gin.H{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+		"expires_in":                int64(deviceCodeTTL.Seconds()),
+		"interval":                  devicePollInterval,
+	})
+}
+
+// DeviceVerification 用户在浏览器中打开的验证页，可通过 verification_uri_complete 预填 user_code
+func DeviceVerification(c *gin.Context) {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		renderDevicePage(c, http.StatusOK, nil, nil, "", "", "")
+		return
+	}
+
+	record, client, err := lookupDeviceCode(c, userCode)
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		renderDeviceThrottled(c, throttled, nil, nil, userCode, "")
+		return
+	}
+	if err != nil {
+		renderDevicePage(c, http.StatusOK, nil, nil, userCode, "", "设备代码无效或已过期")
+		return
+	}
+	renderDevicePage(c, http.StatusOK, record, client, formatUserCode(record.UserCode), "", "")
+}
+
+// DeviceVerificationSubmit 验证页提交：用户名密码（以及两步验证码）通过后批准或拒绝设备
+func DeviceVerificationSubmit(c *gin.Context) {
+	var req DeviceApprovalRequest
+	if err := c.ShouldBind(&req); err != nil {
+		renderDevicePage(c, http.StatusBadRequest, nil, nil, "", "", "请输入设备代码")
+		return
+	}
+
+	record, client, err := lookupDeviceCode(c, req.UserCode)
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		renderDeviceThrottled(c, throttled, nil, nil, req.UserCode, req.Username)
+		return
+	}
+	if err != nil {
+		renderDevicePage(c, http.StatusBadRequest, nil, nil, req.UserCode, req.Username, "设备代码无效或已过期")
+		return
+	}
+
+	if req.Action != "approve" {
+		if err := decideDeviceCode(record, "", false); err != nil {
+			renderDevicePage(c, http.StatusBadRequest, record, client, req.UserCode, req.Username, "设备代码无效或已过期")
+			return
+		}
+		renderDeviceDone(c, "已拒绝该设备的登录请求")
+		return
+	}
+
+	clientInfo := ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	user, err := AuthenticatePassword(c.Request.Context(), req.Username, req.Password, clientInfo)
+	if errors.As(err, &throttled) {
+		renderDeviceThrottled(c, throttled, record, client, req.UserCode, req.Username)
+		return
+	}
+	if errors.Is(err, ErrEmailNotVerified) {
+		renderDevicePage(c, http.StatusForbidden, record, client, req.UserCode, req.Username, "邮箱尚未验证，请先完成邮箱验证")
+		return
+	}
+	if err != nil {
+		renderDevicePage(c, http.StatusUnauthorized, record, client, req.UserCode, req.Username, "用户名或密码错误")
+		return
+	}
+
+	if user.TOTPEnabled {
+		if err := VerifySecondFactor(user, req.OTP); err != nil {
+			recordLoginFailure(c.Request.Context(), user.Username, user.ID, clientInfo, "invalid second factor")
+			renderDevicePage(c, http.StatusUnauthorized, record, client, req.UserCode, req.Username, "两步验证码错误")
+			return
+		}
+	}
+	resetLoginFailures(c.Request.Context(), user.Username)
+
+	if err := decideDeviceCode(record, user.ID, true); err != nil {
+		renderDevicePage(c, http.StatusBadRequest, record, client, req.UserCode, req.Username, "设备代码无效或已过期")
+		return
+	}
+	renderDeviceDone(c, "设备已授权，请回到设备上继续操作")
+}
+
+// ApproveDevice 已登录的客户端（如 IM 网页版）直接批准或拒绝设备，action 为 approve 或 deny
+func ApproveDevice(c *gin.Context) {
+	var req DeviceApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, client, err := lookupDeviceCode(c, req.UserCode)
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		writeThrottled(c, throttled)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备代码无效或已过期"})
+		return
+	}
+
+	approve := req.Action == "approve"
+	if err := decideDeviceCode(record, c.GetString("user_id"), approve); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备代码无效或已过期"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":   client.ID,
+		"client_name": client.Name,
+		"scope":       record.Scope,
+		"approved":    approve,
+	})
+}
+
+// exchangeDeviceCode token 端点的设备授权轮询。授权完成前返回 authorization_pending，
+// 轮询过快返回 slow_down 并把之后的间隔加 5 秒
+func exchangeDeviceCode(c *gin.Context, client *models.Client) {
+	deviceCodeHash := hashToken(c.PostForm("device_code"))
+
+	var record models.DeviceCode
+	if err := database.DB.Where("device_code_hash = ?", deviceCodeHash).First(&record).Error; err != nil || record.ClientID != client.ID {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "device code is invalid"})
+		return
+	}
+
+	if record.UsedAt != nil {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "device code has already been used"})
+		return
+	}
+	if time.Now().After(record.ExpiresAt) {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"expired_token", "device code has expired"})
+		return
+	}
+
+	now := time.Now()
+	if record.LastPolledAt != nil && now.Sub(*record.LastPolledAt) < time.Duration(record.Interval)*time.Second {
+		database.DB.Model(&models.DeviceCode{}).Where("device_code_hash = ?", deviceCodeHash).
+			Updates(map[string]interface{}{"interval": record.Interval + devicePollInterval, "last_polled_at": now})
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"slow_down", "polling too frequently"})
+		return
+	}
+	database.DB.Model(&models.DeviceCode{}).Where("device_code_hash = ?", deviceCodeHash).Update("last_polled_at", now)
+
+	switch record.Status {
+	case deviceCodePending:
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"authorization_pending", "the user has not yet approved the request"})
+		return
+	case deviceCodeDenied:
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"access_denied", "the user denied the request"})
+		return
+	}
+
+	// device_code 只能换一次 token
+	result := database.DB.Model(&models.DeviceCode{}).
+		Where("device_code_hash = ? AND used_at IS NULL", deviceCodeHash).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected != 1 {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "device code has already been used"})
+		return
+	}
+
+	tokens, err := IssueClientTokenPair(record.UserID, client.ID, record.Scope)
+	if err == nil {
+		var authTime time.Time
+		if record.AuthTime != nil {
+			authTime = *record.AuthTime
+		}
+		tokens.IDToken, err = buildIDToken(tokens, "", authTime)
+	}
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue token"})
+		return
+	}
+
+	writeTokenResponse(c, tokens)
+}
+
+// lookupDeviceCode 查找待处理的 user_code。user_code 空间有限，查找前按 IP 检查登录限流，
+// 输错的次数和密码错误一起计入该 IP 的失败次数，防止穷举他人的 user_code
+func lookupDeviceCode(c *gin.Context, userCode string) (*models.DeviceCode, *models.Client, error) {
+	clientInfo := ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	if err := checkLoginThrottle(c.Request.Context(), "", clientInfo); err != nil {
+		return nil, nil, err
+	}
+
+	record, client, err := findPendingDeviceCode(userCode)
+	if err != nil {
+		recordLoginFailure(c.Request.Context(), "", "", clientInfo, "invalid user code")
+		return nil, nil, err
+	}
+	return record, client, nil
+}
+
+func findPendingDeviceCode(userCode string) (*models.DeviceCode, *models.Client, error) {
+	var record models.DeviceCode
+	if err := database.DB.Where("user_code = ? AND status = ?", normalizeUserCode(userCode), deviceCodePending).
+		First(&record).Error; err != nil {
+		return nil, nil, ErrInvalidUserCode
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, nil, ErrInvalidUserCode
+	}
+
+	client := GetClientByID(record.ClientID)
+	if client == nil {
+		return nil, nil, ErrInvalidUserCode
+	}
+	return &record, client, nil
+}
+
+// decideDeviceCode 以 status = pending 为条件更新，同一个 user_code 只能被批准或拒绝一次
+func decideDeviceCode(record *models.DeviceCode, userID string, approve bool) error {
+	updates := map[string]interface{}{"status": deviceCodeDenied}
+	if approve {
+		updates = map[string]interface{}{"status": deviceCodeApproved, "user_id": userID, "auth_time": time.Now()}
+	}
+
+	result := database.DB.Model(&models.DeviceCode{}).
+		Where("device_code_hash = ? AND status = ?", record.DeviceCodeHash, deviceCodePending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func renderDevicePage(c *gin.Context, status int, record *models.DeviceCode, client *models.Client, userCode, username, errMsg string) {
+	data := gin.H{"UserCode": userCode, "Username": username, "Error": errMsg}
+	if record != nil && client != nil {
+		var scopes []string
+		for _, scope := range strings.Fields(record.Scope) {
+			scopes = append(scopes, supportedScopes[scope])
+		}
+		data["ClientName"] = client.Name
+		data["Scopes"] = scopes
+	}
+	writeDevicePage(c, status, data)
+}
+
+func renderDeviceThrottled(c *gin.Context, err *ThrottledError, record *models.DeviceCode, client *models.Client, userCode, username string) {
+	c.Header("Retry-After", strconv.Itoa(err.RetryAfterSeconds()))
+	renderDevicePage(c, http.StatusTooManyRequests, record, client, userCode, username, "尝试次数过多，请稍后再试")
+}
+
+func renderDeviceDone(c *gin.Context, message string) {
+	writeDevicePage(c, http.StatusOK, gin.H{"Done": message})
+}
+
+func writeDevicePage(c *gin.Context, status int, data gin.H) {
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := deviceTemplate.Execute(c.Writer, data); err != nil {
+		log.Printf("Failed to render device page: %v", err)
+	}
+}
+
+// generateUserCode 生成 8 位 user_code，展示时格式化为 XXXX-XXXX
+func generateUserCode() (string, error) {
+	return readCode(rand.Reader, userCodeAlphabet, 8)
+}
+
+// readCode 从 r 读取 n 个字母表中的字符。256 通常不是字母表长度的整数倍，直接取模会让
+// 靠前的字母更常出现，因此丢弃落在最后不完整区间里的字节，再从随机源补读
+func readCode(r io.Reader, alphabet string, n int) (string, error) {
+	limit := 256 - 256%len(alphabet)
+	code := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(code) < n {
+		if _, err := io.ReadFull(r, buf[:n-len(code)]); err != nil {
+			return "", err
+		}
+		for _, b := range buf[:n-len(code)] {
+			if int(b) < limit {
+				code = append(code, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+func formatUserCode(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// normalizeUserCode 忽略大小写、空格和连字符，方便用户输入
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/database/dbtest"
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadCode(t *testing.T) {
+	// 240 及以上的字节被丢弃并补读；19、39 和 239 都映射到字母表最后一个字母
+	random := []byte{240, 0, 255, 1, 19, 20, 39, 250, 239, 245, 100, 21}
+	code, err := readCode(bytes.NewReader(random), userCodeAlphabet, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "BCZBZZBC"; code != want {
+		t.Fatalf("readCode() = %q, want %q", code, want)
+	}
+
+	if _, err := readCode(bytes.NewReader([]byte{255, 255, 0}), userCodeAlphabet, 8); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("readCode() on a short source = %v, want ErrUnexpectedEOF", err)
+	}
+}
+
+func TestGenerateUserCodeIsUniform(t *testing.T) {
+	const samples = 20000
+	counts := make(map[rune]int)
+	for i := 0; i < samples/8; i++ {
+		code, err := generateUserCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 8 {
+			t.Fatalf("generateUserCode() = %q", code)
+		}
+		for _, r := range code {
+			counts[r]++
+		}
+	}
+
+	if len(counts) != len(userCodeAlphabet) {
+		t.Fatalf("got %d distinct letters, want %d", len(counts), len(userCodeAlphabet))
+	}
+	// 取模的偏差会让前 16 个字母多出约 25%，这里的容差远小于偏差
+	expected := float64(samples) / float64(len(userCodeAlphabet))
+	for r, n := range counts {
+		if !strings.ContainsRune(userCodeAlphabet, r) {
+			t.Errorf("letter %q not in alphabet", r)
+		}
+		if float64(n) < expected*0.85 || float64(n) > expected*1.15 {
+			t.Errorf("letter %q appeared %d times, want about %.0f", r, n, expected)
+		}
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	for _, input := range []string{"BCDF-GHJK", "bcdf-ghjk", " bcdf ghjk ", "BCDFGHJK"} {
+		if got := normalizeUserCode(input); got != "BCDFGHJK" {
+			t.Errorf("normalizeUserCode(%q) = %q", input, got)
+		}
+	}
+	if got := formatUserCode("BCDFGHJK"); got != "BCDF-GHJK" {
+		t.Errorf("formatUserCode() = %q", got)
+	}
+}
+
+// setupDeviceTest 准备数据库、独立的限流计数和一个待批准的设备代码
+func setupDeviceTest(t *testing.T) *models.DeviceCode {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dbtest.Setup(t)
+	ratelimit.Init(nil)
+	t.Cleanup(func() { ratelimit.Init(nil) })
+
+	client := &models.Client{ID: "tv-app", Name: "TV", Scopes: "openid profile", Public: true}
+	if err := database.DB.Create(client).Error; err != nil {
+		t.Fatal(err)
+	}
+	record := &models.DeviceCode{
+		DeviceCodeHash: hashToken("device-code"),
+		UserCode:       "BCDFGHJK",
+		ClientID:       client.ID,
+		Scope:          "openid",
+		Status:         deviceCodePending,
+		Interval:       devicePollInterval,
+		ExpiresAt:      time.Now().Add(deviceCodeTTL),
+		CreatedAt:      time.Now(),
+	}
+	if err := database.DB.Create(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func submitDeviceForm(router http.Handler, ip string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/device", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func deviceStatus(t *testing.T, record *models.DeviceCode) string {
+	t.Helper()
+	var stored models.DeviceCode
+	if err := database.DB.Where("device_code_hash = ?", record.DeviceCodeHash).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored.Status
+}
+
+// 猜错 user_code 计入该 IP 的登录失败，超过阈值后即使代码正确也被拒绝，其他 IP 不受影响
+func TestDeviceVerificationSubmitThrottlesUserCodeGuesses(t *testing.T) {
+	record := setupDeviceTest(t)
+	router := gin.New()
+	router.POST("/device", DeviceVerificationSubmit)
+
+	const attacker = "198.51.100.7"
+	for i := 0; i <= ipThrottle.delayAfter; i++ {
+		w := submitDeviceForm(router, attacker, url.Values{"user_code": {"ZZZZ-ZZZZ"}, "action": {"deny"}})
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("guess %d: status = %d, want %d", i, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := submitDeviceForm(router, attacker, url.Values{"user_code": {formatUserCode(record.UserCode)}, "action": {"deny"}})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("throttled status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if status := deviceStatus(t, record); status != deviceCodePending {
+		t.Fatalf("throttled request changed status to %s", status)
+	}
+
+	var failures int64
+	database.DB.Model(&models.AuditEvent{}).Where("type = ? AND ip = ? AND detail = ?", AuditLoginFailed, attacker, "invalid user code").Count(&failures)
+	if failures != int64(ipThrottle.delayAfter+1) {
+		t.Errorf("%d audited user_code failures, want %d", failures, ipThrottle.delayAfter+1)
+	}
+
+	w = submitDeviceForm(router, "203.0.113.9", url.Values{"user_code": {strings.ToLower(record.UserCode)}, "action": {"deny"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("other IP status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if status := deviceStatus(t, record); status != deviceCodeDenied {
+		t.Fatalf("status = %s, want %s", status, deviceCodeDenied)
+	}
+}
+
+func TestDeviceVerificationThrottlesLookups(t *testing.T) {
+	record := setupDeviceTest(t)
+	router := gin.New()
+	router.GET("/device", DeviceVerification)
+
+	lookup := func(userCode string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/device?"+url.Values{"user_code": {userCode}}.Encode(), nil)
+		req.RemoteAddr = "198.51.100.7:12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := lookup(record.UserCode); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "TV") {
+		t.Fatalf("valid lookup = %d %s", w.Code, w.Body.String())
+	}
+	for i := 0; i <= ipThrottle.delayAfter; i++ {
+		lookup("ZZZZZZZZ")
+	}
+	if w := lookup(record.UserCode); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("lookup after guesses = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestDecideDeviceCodeOnce(t *testing.T) {
+	record := setupDeviceTest(t)
+
+	if err := decideDeviceCode(record, "user-1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := decideDeviceCode(record, "", false); err == nil {
+		t.Fatal("approved device code was denied afterwards")
+	}
+	if status := deviceStatus(t, record); status != deviceCodeApproved {
+		t.Fatalf("status = %s, want %s", status, deviceCodeApproved)
+	}
+
+	// 已经处理过的 user_code 不能再被找到
+	if _, _, err := findPendingDeviceCode(record.UserCode); !errors.Is(err, ErrInvalidUserCode) {
+		t.Fatalf("findPendingDeviceCode() = %v, want ErrInvalidUserCode", err)
+	}
+}
+
+func TestFindPendingDeviceCodeExpired(t *testing.T) {
+	record := setupDeviceTest(t)
+	database.DB.Model(&models.DeviceCode{}).Where("device_code_hash = ?", record.DeviceCodeHash).
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	if _, _, err := findPendingDeviceCode(record.UserCode); !errors.Is(err, ErrInvalidUserCode) {
+		t.Fatalf("findPendingDeviceCode() = %v, want ErrInvalidUserCode", err)
+	}
+}
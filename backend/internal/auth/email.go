@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/mailer"
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = 30 * time.Minute
+	mailSendTimeout      = 30 * time.Second
+)
+
+// 每个邮箱、每个 IP 在窗口内能触发的邮件数，防止接口被用来轰炸邮箱或拖垮邮件服务
+var (
+	mailThrottleWindow = time.Hour
+	mailPerEmailLimit  = 3
+	mailPerIPLimit     = 10
+)
+
+var (
+	ErrEmailNotVerified     = errors.New("email not verified")
+	ErrInvalidEmailToken    = errors.New("invalid email verification token")
+	ErrInvalidPasswordReset = errors.New("invalid password reset token")
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmail 校验邮件中的链接 token，把待验证的账号激活
+func VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := ConfirmEmail(req.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidEmailToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "验证链接无效或已过期"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "验证邮箱失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"message":  "邮箱验证成功",
+	})
+}
+
+// ResendVerification 重新发送验证邮件。无论邮箱是否存在都返回相同结果，避免被用来探测账号
+func ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 被限流时同样返回成功，不发信
+	var user models.User
+	if allowMail(c, purposeVerifyEmail, req.Email) &&
+		database.DB.Where("email = ? AND status = ?", req.Email, models.UserStatusPending).First(&user).Error == nil {
+		go sendMail(func(ctx context.Context) error { return SendVerificationEmail(ctx, &user) })
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且未验证，验证邮件已发送"})
+}
+
+// ForgotPassword 发送密码重置邮件，同样不暴露邮箱是否存在
+func ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 外部身份源的用户密码由目录管理，不能在这里重置；被限流时同样返回成功，不发信
+	var user models.User
+	if allowMail(c, purposeResetPassword, req.Email) &&
+		database.DB.Where("email = ?", req.Email).First(&user).Error == nil && userProvider(&user) == ProviderLocal {
+		go sendMail(func(ctx context.Context) error { return SendPasswordResetEmail(ctx, &user) })
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，重置密码邮件已发送"})
+}
+
+// ResetPassword 用邮件中的 token 设置新密码，成功后该用户所有设备都需要重新登录
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	if err := ResetPasswordWithToken(c.Request.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, ErrInvalidPasswordReset) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "重置链接无效或已过期"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
+
+// SendVerificationEmail 签发绑定当前邮箱地址的验证 token 并发送邮件
+func SendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := jwt.GeneratePurposeToken(user.ID, purposeVerifyEmail, emailVerificationTTL, map[string]string{
+		"email": user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := mailer.Link("/verify-email?token=" + url.QueryEscape(token))
+	return mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "请验证你的 IM 邮箱",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %d 小时内打开以下链接完成邮箱验证：\n\n%s\n\n如果这不是你的操作，请忽略这封邮件。\n",
+			user.Username, int(emailVerificationTTL.Hours()), link),
+	})
+}
+
+// SendPasswordResetEmail 签发密码重置 token 并发送邮件。token 绑定当前密码哈希的指纹，
+// 密码一旦修改，之前发出的重置链接全部失效
+func SendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := jwt.GeneratePurposeToken(user.ID, purposeResetPassword, passwordResetTTL, map[string]string{
+		"pwd": passwordFingerprint(user.PasswordHash),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := mailer.Link("/reset-password?token=" + url.QueryEscape(token))
+	return mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "重置你的 IM 密码",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n如果这不是你的操作，请忽略这封邮件，你的密码不会被修改。\n",
+			user.Username, int(passwordResetTTL.Minutes()), link),
+	})
+}
+
+// ConfirmEmail 校验验证 token，token 签发后邮箱被修改过则视为无效
+func ConfirmEmail(token string) (*models.User, error) {
+	claims, err := jwt.ValidatePurposeToken(token, purposeVerifyEmail)
+	if err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+
+	user := GetUserByID(claims.Subject)
+	if user == nil || user.Email != claims.Data["email"] {
+		return nil, ErrInvalidEmailToken
+	}
+	if user.Status != models.UserStatusPending {
+		return user, nil
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ? AND status = ?", user.ID, models.UserStatusPending).
+		Updates(map[string]interface{}{"status": models.UserStatusActive, "updated_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+	user.Status = models.UserStatusActive
+	return user, nil
+}
+
+// ResetPasswordWithToken 校验重置 token 并修改密码，随后吊销该用户的所有会话和 refresh token。
+// 能收到重置邮件也证明了邮箱归属，待验证的账号会一并激活
+func ResetPasswordWithToken(ctx context.Context, token, password string) error {
+	claims, err := jwt.ValidatePurposeToken(token, purposeResetPassword)
+	if err != nil {
+		return ErrInvalidPasswordReset
+	}
+
+	user := GetUserByID(claims.Subject)
+	if user == nil || userProvider(user) != ProviderLocal || passwordFingerprint(user.PasswordHash) != claims.Data["pwd"] {
+		return ErrInvalidPasswordReset
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// 以旧密码哈希为条件更新，并发使用同一个 token 时只有一个请求能成功
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", user.ID, user.PasswordHash).
+		Updates(map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"status":        models.UserStatusActive,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidPasswordReset
+	}
+
+	return revokeAllSessions(ctx, user.ID)
+}
+
+// revokeAllSessions 吊销用户的全部设备会话以及第三方应用持有的 refresh token
+func revokeAllSessions(ctx context.Context, userID string) error {
+	sessions, err := ListSessions(userID)
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		if err := revokeSession(ctx, &sessions[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := database.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	// 没有会话的旧 token 无法按 sid 吊销，断开该用户剩余的连接
+	if err := revocation.Publish(ctx, revocation.Event{UserID: userID}); err != nil {
+		log.Printf("Failed to publish revocation for user %s: %v", userID, err)
+	}
+	return nil
+}
+
+// sendMail 在后台发送邮件，请求的响应时间不随邮件是否发送而变化
+// allowMail 按收件邮箱和请求 IP 计数，任一超过上限时不再发信。
+// 无论邮箱是否存在都计数，限流存储不可用时放行
+func allowMail(c *gin.Context, purpose, email string) bool {
+	allowed := true
+	limits := map[string]int{
+		mailThrottleKey(purpose, "email", email):     mailPerEmailLimit,
+		mailThrottleKey(purpose, "ip", c.ClientIP()): mailPerIPLimit,
+	}
+	for key, limit := range limits {
+		count, err := ratelimit.Hit(c.Request.Context(), key, mailThrottleWindow)
+		if err != nil {
+			log.Printf("Failed to check mail throttle %s: %v", key, err)
+			continue
+		}
+		if count > limit {
+			allowed = false
+		}
+	}
+	if !allowed {
+		log.Printf("Mail throttled: purpose=%s, email=%s, ip=%s", purpose, email, c.ClientIP())
+	}
+	return allowed
+}
+
+func mailThrottleKey(purpose, kind, value string) string {
+	return "mail:" + purpose + ":" + kind + ":" + strings.ToLower(value)
+}
+
+func sendMail(send func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+	if err := send(ctx); err != nil {
+		log.Printf("Failed to send mail: %v", err)
+	}
+}
+
+func passwordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(sum[:8])
+}
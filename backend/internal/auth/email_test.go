@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/pkg/mailer"
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// setupMailTest 把邮件写到临时目录，返回统计已发送邮件数的函数
+func setupMailTest(t *testing.T) func() int {
+	t.Helper()
+	setupTokenTest(t)
+	ratelimit.Init(nil)
+	dir := t.TempDir()
+	if err := mailer.Init(mailer.Config{Driver: "file", Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ratelimit.Init(nil)
+		mailer.Init(mailer.Config{})
+	})
+
+	return func() int {
+		entries, _ := os.ReadDir(dir)
+		return len(entries)
+	}
+}
+
+func postEmail(router http.Handler, path, ip, email string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// waitForMail 发信在后台进行，等到邮件数达到 want 或超时
+func waitForMail(t *testing.T, sent func() int, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for sent() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 多等一会，确认没有多余的邮件
+	time.Sleep(50 * time.Millisecond)
+	if got := sent(); got != want {
+		t.Fatalf("%d mails sent, want %d", got, want)
+	}
+}
+
+// 同一邮箱超过限额后不再发信，但响应与正常情况相同，不暴露是否被限流
+func TestForgotPasswordThrottlesPerEmail(t *testing.T) {
+	sent := setupMailTest(t)
+	user := createTestUser(t, "user-1", "alice")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/forgot", ForgotPassword)
+
+	first := postEmail(router, "/forgot", "198.51.100.0", user.Email)
+	for i := 1; i <= mailPerEmailLimit; i++ {
+		// 换 IP 也绕不过邮箱的限额
+		w := postEmail(router, "/forgot", fmt.Sprintf("198.51.100.%d", i), user.Email)
+		if w.Code != http.StatusOK || w.Body.String() != first.Body.String() {
+			t.Fatalf("request %d = %d %s, want %s", i, w.Code, w.Body.String(), first.Body.String())
+		}
+	}
+	waitForMail(t, sent, mailPerEmailLimit)
+
+	// 邮箱不区分大小写
+	if allowMail(newTestContext("203.0.113.9"), purposeResetPassword, strings.ToUpper(user.Email)) {
+		t.Fatal("upper-case email not throttled")
+	}
+}
+
+func TestResendVerificationThrottlesPerIP(t *testing.T) {
+	sent := setupMailTest(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/resend", ResendVerification)
+
+	const attacker = "198.51.100.7"
+	for i := 0; i < mailPerIPLimit; i++ {
+		postEmail(router, "/resend", attacker, fmt.Sprintf("nobody%d@example.com", i))
+	}
+	if !allowMail(newTestContext("203.0.113.9"), purposeVerifyEmail, "other@example.com") {
+		t.Fatal("other IP throttled")
+	}
+	if allowMail(newTestContext(attacker), purposeVerifyEmail, "fresh@example.com") {
+		t.Fatal("IP not throttled after reaching the limit")
+	}
+	// 重置密码的限额与验证邮件分开计算
+	if !allowMail(newTestContext(attacker), purposeResetPassword, "fresh@example.com") {
+		t.Fatal("password reset throttled by verification requests")
+	}
+	waitForMail(t, sent, 0)
+}
+
+func newTestContext(ip string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Request.RemoteAddr = ip + ":12345"
+	return c
+}
@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/cyperlo/im/internal/models"
 	"github.com/cyperlo/im/pkg/database"
 	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -19,8 +23,20 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	DeviceID     string `json:"device_id" binding:"required"`
 }
 
 func Register(c *gin.Context) {
@@ -54,7 +70,8 @@ func Register(c *gin.Context) {
 		Username:     req.Username,
 		PasswordHash: string(hashedPassword),
 		Email:        req.Email,
-		Status:       "active",
+		Status:       models.UserStatusPending,
+		AuthProvider: ProviderLocal,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -64,11 +81,17 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 与重发验证邮件共用限额，超出时账号照常创建，之后可以重新发送
+	if allowMail(c, purposeVerifyEmail, user.Email) {
+		go sendMail(func(ctx context.Context) error { return SendVerificationEmail(ctx, user) })
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"user_id":  user.ID,
 		"username": user.Username,
 		"email":    user.Email,
-		"message":  "注册成功",
+		"status":   user.Status,
+		"message":  "注册成功，请查收验证邮件",
 	})
 }
 
@@ -79,40 +102,149 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	user, err := AuthenticatePassword(c.Request.Context(), req.Username, req.Password, ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		writeThrottled(c, throttled)
+		return
+	}
+	if errors.Is(err, ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "邮箱尚未验证，请先完成邮箱验证", "code": "email_not_verified"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+	info := SessionInfo{
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		Platform:   req.Platform,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+
+	// 开启了两步验证时密码只完成第一步，客户端需携带 mfa_token 调用 /login/2fa
+	if user.TOTPEnabled {
+		mfaToken, err := CreateMFAChallenge(user, info)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int64(mfaChallengeTTL.Seconds()),
+		})
 		return
 	}
 
-	token, err := jwt.GenerateToken(user.ID)
+	completeLogin(c, user, info)
+}
+
+// completeLogin 身份校验全部通过后清空失败计数，创建会话并返回 token
+func completeLogin(c *gin.Context, user *models.User, info SessionInfo) {
+	resetLoginFailures(c.Request.Context(), user.Username)
+
+	session, err := CreateSession(c.Request.Context(), user.ID, info)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败"})
+		return
+	}
+
+	tokens, err := IssueTokenPair(session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":    token,
-		"user_id":  user.ID,
-		"username": user.Username,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"device_id":     tokens.DeviceID,
+		"session_id":    tokens.SessionID,
+		"user_id":       user.ID,
+		"username":      user.Username,
 	})
 }
 
 func RefreshToken(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"token": "new_token"})
-}
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-func Authorize(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": "auth_code"})
+	tokens, err := RotateRefreshToken(req.RefreshToken, "", req.DeviceID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token 无效或已过期，请重新登录"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新 token 失败"})
+		return
+	}
+
+	TouchSession(tokens.SessionID, c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"device_id":     tokens.DeviceID,
+	})
 }
 
-func Token(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"access_token": "access_token"})
+// Logout 吊销当前会话；没有会话的旧 token 则吊销 token 本身，并在提供 refresh_token 时吊销整个登录 family
+func Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt.Claims)
+
+	if claims.SessionID != "" {
+		if err := RevokeSession(c.Request.Context(), claims.UserID, claims.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			log.Printf("Failed to revoke session %s: %v", claims.SessionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+		return
+	}
+
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if req.RefreshToken != "" {
+		token := FindRefreshToken(req.RefreshToken)
+		if token != nil && token.UserID == claims.UserID && token.ClientID == "" {
+			if err := RevokeTokenFamily(token.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+				return
+			}
+		}
+	}
+
+	if err := revocation.RevokeToken(c.Request.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+		log.Printf("Failed to revoke token %s: %v", claims.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+
+	// WebSocket 连接可能是用更早的 access token 建立的，因此断开该用户的全部连接，
+	// 其他设备会用各自仍然有效的 token 自动重连
+	if err := revocation.Publish(c.Request.Context(), revocation.Event{UserID: claims.UserID, TokenID: claims.ID}); err != nil {
+		log.Printf("Failed to publish revocation for user %s: %v", claims.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
 }
 
 func GetUserByID(userID string) *models.User {
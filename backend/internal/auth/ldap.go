@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ProviderLDAP = "ldap"
+
+// LDAPConfig 两种认证方式：
+//   - 配置 UserDNTemplate（如 uid=%s,ou=people,dc=example,dc=com）时直接用拼出的 DN 绑定；
+//   - 否则先用 BindDN/BindPassword 在 BaseDN 下按 UserFilter 查找用户 DN，再用用户密码绑定。
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	UserDNTemplate     string
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	EmailAttribute     string
+	Timeout            time.Duration
+}
+
+// LDAPProvider 通过 LDAP simple bind 校验密码
+type LDAPProvider struct {
+	config LDAPConfig
+}
+
+func NewLDAPProvider(config LDAPConfig) (*LDAPProvider, error) {
+	if config.URL == "" {
+		return nil, errors.New("ldap provider requires LDAP_URL")
+	}
+	if config.UserDNTemplate == "" && config.BaseDN == "" {
+		return nil, errors.New("ldap provider requires LDAP_USER_DN_TEMPLATE or LDAP_BASE_DN")
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(uid=%s)"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	return &LDAPProvider{config: config}, nil
+}
+
+func (p *LDAPProvider) Name() string {
+	return ProviderLDAP
+}
+
+func (p *LDAPProvider) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// 空密码会被 LDAP 当作匿名绑定并返回成功，必须在这里拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	userDN, email, err := p.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(userDN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap bind failed: %w", err)
+	}
+
+	// 直接绑定模式下绑定成功后再读取邮箱
+	if p.config.UserDNTemplate != "" {
+		email = p.readEmail(conn, userDN)
+	}
+
+	return &Identity{Provider: ProviderLDAP, Username: username, Email: email}, nil
+}
+
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(p.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial failed: %w", err)
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// findUser 返回用户的 DN，查找模式下同时返回邮箱
+func (p *LDAPProvider) findUser(conn *ldap.Conn, username string) (string, string, error) {
+	if p.config.UserDNTemplate != "" {
+		return fmt.Sprintf(p.config.UserDNTemplate, ldap.EscapeDN(username)), "", nil
+	}
+
+	if p.config.BindDN != "" {
+		if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+			return "", "", fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(p.config.Timeout.Seconds()), false,
+		fmt.Sprintf(p.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", p.config.EmailAttribute}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return "", "", ErrInvalidCredentials
+		}
+		return "", "", fmt.Errorf("ldap search failed: %w", err)
+	}
+
+	// 找不到或匹配到多个条目都不能确定是哪个用户
+	if len(result.Entries) != 1 {
+		return "", "", ErrInvalidCredentials
+	}
+
+	entry := result.Entries[0]
+	return entry.DN, entry.GetAttributeValue(p.config.EmailAttribute), nil
+}
+
+func (p *LDAPProvider) readEmail(conn *ldap.Conn, userDN string) string {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		userDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(p.config.Timeout.Seconds()), false,
+		"(objectClass=*)", []string{p.config.EmailAttribute}, nil,
+	))
+	if err != nil || len(result.Entries) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(result.Entries[0].GetAttributeValue(p.config.EmailAttribute))
+}
@@ -0,0 +1,409 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/database/dbtest"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP 协议操作（RFC 4511）和结果码
+const (
+	ldapBindRequest   ber.Tag = 0
+	ldapBindResponse  ber.Tag = 1
+	ldapUnbindRequest ber.Tag = 2
+	ldapSearchRequest ber.Tag = 3
+	ldapSearchEntry   ber.Tag = 4
+	ldapSearchDone    ber.Tag = 5
+
+	ldapSuccess            = 0
+	ldapProtocolError      = 2
+	ldapNoSuchObject       = 32
+	ldapInvalidCredentials = 49
+	ldapUnwillingToPerform = 53
+)
+
+const (
+	testServiceDN       = "cn=svc,dc=example,dc=com"
+	testServicePassword = "svc-secret"
+	testPeopleDN        = "ou=people,dc=example,dc=com"
+	testContractorsDN   = "ou=contractors,dc=example,dc=com"
+	testAliceDN         = "uid=alice," + testPeopleDN
+	testAlicePassword   = "alice-secret"
+	testAliceEmail      = "alice@example.com"
+	testBobPeopleDN     = "uid=bob," + testPeopleDN
+	testBobContractorDN = "uid=bob," + testContractorsDN
+	testBobPassword     = "bob-secret"
+)
+
+type fakeLDAPEntry struct {
+	password string
+	attrs    map[string]string
+}
+
+// fakeLDAPServer 进程内的最小 LDAP 服务，只实现 simple bind 和单个等值条件的搜索
+type fakeLDAPServer struct {
+	listener net.Listener
+	entries  map[string]fakeLDAPEntry
+
+	mu    sync.Mutex
+	conns int
+	binds []string
+}
+
+func newFakeLDAPServer(t *testing.T) *fakeLDAPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeLDAPServer{
+		listener: listener,
+		entries: map[string]fakeLDAPEntry{
+			testServiceDN:       {password: testServicePassword},
+			testAliceDN:         {password: testAlicePassword, attrs: map[string]string{"uid": "alice", "mail": testAliceEmail}},
+			testBobPeopleDN:     {password: testBobPassword, attrs: map[string]string{"uid": "bob"}},
+			testBobContractorDN: {password: testBobPassword, attrs: map[string]string{"uid": "bob"}},
+		},
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAPServer) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, append([]string(nil), s.binds...)
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldapBindRequest:
+			responses = append(responses, s.bind(messageID, op))
+		case ldapSearchRequest:
+			responses = s.search(messageID, op)
+		case ldapUnbindRequest:
+			return
+		default:
+			responses = append(responses, ldapResponse(messageID, ldapSearchDone, ldapProtocolError))
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *fakeLDAPServer) bind(messageID int64, op *ber.Packet) *ber.Packet {
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+
+	s.mu.Lock()
+	s.binds = append(s.binds, dn)
+	s.mu.Unlock()
+
+	code := ldapInvalidCredentials
+	switch {
+	case password == "":
+		// 与真实目录一样把空密码当作匿名绑定并返回成功，Authenticate 不能依赖服务端拒绝
+		code = ldapSuccess
+	case s.entries[dn].password == password:
+		code = ldapSuccess
+	}
+	return ldapResponse(messageID, ldapBindResponse, code)
+}
+
+func (s *fakeLDAPServer) search(messageID int64, op *ber.Packet) []*ber.Packet {
+	baseDN, _ := op.Children[0].Value.(string)
+	scope, _ := op.Children[1].Value.(int64)
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		return []*ber.Packet{ldapResponse(messageID, ldapSearchDone, ldapProtocolError)}
+	}
+
+	var responses []*ber.Packet
+	if scope == ldap.ScopeBaseObject {
+		entry, ok := s.entries[baseDN]
+		if !ok {
+			return []*ber.Packet{ldapResponse(messageID, ldapSearchDone, ldapNoSuchObject)}
+		}
+		responses = append(responses, ldapSearchResultEntry(messageID, baseDN, entry))
+	} else {
+		attr, value, ok := strings.Cut(strings.Trim(filter, "()"), "=")
+		if !ok {
+			return []*ber.Packet{ldapResponse(messageID, ldapSearchDone, ldapUnwillingToPerform)}
+		}
+		for dn, entry := range s.entries {
+			if strings.HasSuffix(dn, ","+baseDN) && entry.attrs[attr] == value {
+				responses = append(responses, ldapSearchResultEntry(messageID, dn, entry))
+			}
+		}
+	}
+	return append(responses, ldapResponse(messageID, ldapSearchDone, ldapSuccess))
+}
+
+func ldapEnvelope(messageID int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, ""))
+	packet.AppendChild(op)
+	return packet
+}
+
+func ldapResponse(messageID int64, application ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, application, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return ldapEnvelope(messageID, op)
+}
+
+func ldapSearchResultEntry(messageID int64, dn string, entry fakeLDAPEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, value := range entry.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+		attr.AppendChild(values)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return ldapEnvelope(messageID, op)
+}
+
+func newTestLDAPProvider(t *testing.T, server *fakeLDAPServer, config LDAPConfig) *LDAPProvider {
+	t.Helper()
+	config.URL = server.url()
+	config.Timeout = 2 * time.Second
+	provider, err := NewLDAPProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestLDAPProviderAuthenticate(t *testing.T) {
+	server := newFakeLDAPServer(t)
+
+	directBind := LDAPConfig{UserDNTemplate: "uid=%s," + testPeopleDN}
+	searchBind := LDAPConfig{BindDN: testServiceDN, BindPassword: testServicePassword, BaseDN: "dc=example,dc=com"}
+
+	tests := []struct {
+		name      string
+		config    LDAPConfig
+		username  string
+		password  string
+		wantErr   error
+		wantEmail string
+		wantBinds []string
+	}{
+		{
+			name:      "direct dn bind",
+			config:    directBind,
+			username:  "alice",
+			password:  testAlicePassword,
+			wantEmail: testAliceEmail,
+			wantBinds: []string{testAliceDN},
+		},
+		{
+			name:      "direct dn bind with wrong password",
+			config:    directBind,
+			username:  "alice",
+			password:  "wrong",
+			wantErr:   ErrInvalidCredentials,
+			wantBinds: []string{testAliceDN},
+		},
+		{
+			name:      "search then bind",
+			config:    searchBind,
+			username:  "alice",
+			password:  testAlicePassword,
+			wantEmail: testAliceEmail,
+			wantBinds: []string{testServiceDN, testAliceDN},
+		},
+		{
+			name:      "search then bind with wrong password",
+			config:    searchBind,
+			username:  "alice",
+			password:  "wrong",
+			wantErr:   ErrInvalidCredentials,
+			wantBinds: []string{testServiceDN, testAliceDN},
+		},
+		{
+			name:      "search without match",
+			config:    searchBind,
+			username:  "carol",
+			password:  "whatever",
+			wantErr:   ErrInvalidCredentials,
+			wantBinds: []string{testServiceDN},
+		},
+		{
+			name:      "search with multiple matches",
+			config:    searchBind,
+			username:  "bob",
+			password:  testBobPassword,
+			wantErr:   ErrInvalidCredentials,
+			wantBinds: []string{testServiceDN},
+		},
+		{
+			name:     "empty password",
+			config:   directBind,
+			username: "alice",
+			password: "",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "empty username",
+			config:   searchBind,
+			username: "",
+			password: testAlicePassword,
+			wantErr:  ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestLDAPProvider(t, server, tt.config)
+			connsBefore, bindsBefore := server.stats()
+
+			identity, err := provider.Authenticate(context.Background(), tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if identity.Provider != ProviderLDAP || identity.Username != tt.username || identity.Email != tt.wantEmail {
+					t.Errorf("Authenticate() = %+v", identity)
+				}
+			}
+
+			conns, binds := server.stats()
+			binds = binds[len(bindsBefore):]
+			if len(tt.wantBinds) == 0 && conns != connsBefore {
+				t.Errorf("Authenticate() dialed the directory %d times, want none", conns-connsBefore)
+			}
+			if strings.Join(binds, "|") != strings.Join(tt.wantBinds, "|") {
+				t.Errorf("binds = %q, want %q", binds, tt.wantBinds)
+			}
+		})
+	}
+}
+
+func TestLDAPProviderUnreachable(t *testing.T) {
+	server := newFakeLDAPServer(t)
+	provider := newTestLDAPProvider(t, server, LDAPConfig{UserDNTemplate: "uid=%s," + testPeopleDN})
+	server.listener.Close()
+
+	_, err := provider.Authenticate(context.Background(), "alice", testAlicePassword)
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want a dial error", err)
+	}
+}
+
+func TestLDAPAutoProvisioning(t *testing.T) {
+	dbtest.Setup(t)
+	server := newFakeLDAPServer(t)
+
+	old := identityProviders
+	identityProviders = []IdentityProvider{
+		&LocalProvider{},
+		newTestLDAPProvider(t, server, LDAPConfig{BindDN: testServiceDN, BindPassword: testServicePassword, BaseDN: testPeopleDN}),
+	}
+	t.Cleanup(func() { identityProviders = old })
+
+	if GetUserByUsername("alice") != nil {
+		t.Fatal("alice exists before first login")
+	}
+
+	user, err := authenticateIdentity(context.Background(), "alice", testAlicePassword)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if user.AuthProvider != ProviderLDAP || user.Email != testAliceEmail || user.PasswordHash != "" {
+		t.Errorf("provisioned user = %+v", user)
+	}
+
+	stored := GetUserByUsername("alice")
+	if stored == nil || stored.ID != user.ID {
+		t.Fatalf("provisioned user not stored: %+v", stored)
+	}
+
+	again, err := authenticateIdentity(context.Background(), "alice", testAlicePassword)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second login created another user %s, want %s", again.ID, user.ID)
+	}
+
+	var count int64
+	database.DB.Model(&models.User{}).Where("username = ?", "alice").Count(&count)
+	if count != 1 {
+		t.Errorf("%d users named alice, want 1", count)
+	}
+
+	if _, err := authenticateIdentity(context.Background(), "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPIdentityCannotTakeOverLocalUser(t *testing.T) {
+	dbtest.Setup(t)
+	server := newFakeLDAPServer(t)
+
+	local := &models.User{ID: "local-alice", Username: "alice", Status: models.UserStatusActive, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := database.DB.Create(local).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	old := identityProviders
+	identityProviders = []IdentityProvider{
+		newTestLDAPProvider(t, server, LDAPConfig{UserDNTemplate: "uid=%s," + testPeopleDN}),
+	}
+	t.Cleanup(func() { identityProviders = old })
+
+	if _, err := authenticateIdentity(context.Background(), "alice", testAlicePassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("login error = %v, want ErrInvalidCredentials", err)
+	}
+}
@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/cyperlo/im/pkg/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer        = "IM"
+	mfaChallengeTTL   = 5 * time.Minute
+	purposeMFALogin   = "mfa_login"
+	recoveryCodeCount = 10
+)
+
+// 恢复码字母表去掉了容易混淆的 0/o、1/l/i
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+var (
+	ErrInvalidOTP         = errors.New("invalid verification code")
+	ErrTOTPAlreadyEnabled = errors.New("totp already enabled")
+	ErrTOTPNotEnrolled    = errors.New("totp not enrolled")
+	ErrInvalidMFAToken    = errors.New("invalid mfa token")
+)
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// GetMFAStatus 返回当前用户两步验证的开启状态和剩余恢复码数量
+func GetMFAStatus(c *gin.Context) {
+	user := GetUserByID(c.GetString("user_id"))
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	var remaining int64
+	database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"totp_enabled":             user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTOTP 生成新的 TOTP 密钥，用户需用验证码确认后才会真正开启
+func SetupTOTP(c *gin.Context) {
+	user := GetUserByID(c.GetString("user_id"))
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "已开启两步验证"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存密钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// EnableTOTP 校验验证器应用生成的第一个验证码，成功后开启两步验证并返回恢复码（只展示这一次）
+func EnableTOTP(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := GetUserByID(c.GetString("user_id"))
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "已开启两步验证"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先获取两步验证密钥"})
+		return
+	}
+
+	if err := verifyTOTP(user, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "开启两步验证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "已开启两步验证",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP 关闭两步验证，需要同时提供密码和验证码（或恢复码）
+func DisableTOTP(c *gin.Context) {
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := GetUserByID(c.GetString("user_id"))
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未开启两步验证"})
+		return
+	}
+
+	if err := verifyUserPassword(c.Request.Context(), user, req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		return
+	}
+	if err := VerifySecondFactor(user, req.Code); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关闭两步验证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭两步验证"})
+}
+
+// RegenerateRecoveryCodes 作废旧的恢复码并生成一组新的，需要当前的 TOTP 验证码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := GetUserByID(c.GetString("user_id"))
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未开启两步验证"})
+		return
+	}
+
+	if err := verifyTOTP(user, req.Code); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginMFA 两步登录的第二步：用第一步返回的 mfa_token 和验证码（或恢复码）换取 token
+func LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, info, err := CompleteMFAChallenge(c.Request.Context(), req.MFAToken, req.Code, ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		var throttled *ThrottledError
+		if errors.As(err, &throttled) {
+			writeThrottled(c, throttled)
+			return
+		}
+		if errors.Is(err, ErrInvalidMFAToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "登录已过期，请重新输入用户名和密码"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	info.IP = c.ClientIP()
+	info.UserAgent = c.Request.UserAgent()
+	completeLogin(c, user, info)
+}
+
+// CreateMFAChallenge 密码校验通过但开启了两步验证时，签发一个短期的 mfa_token，
+// 设备信息随 token 带到第二步
+func CreateMFAChallenge(user *models.User, info SessionInfo) (string, error) {
+	return jwt.GeneratePurposeToken(user.ID, purposeMFALogin, mfaChallengeTTL, map[string]string{
+		"device_id":   info.DeviceID,
+		"device_name": info.DeviceName,
+		"platform":    info.Platform,
+	})
+}
+
+// CompleteMFAChallenge 校验 mfa_token 和第二因素，成功后 mfa_token 立即作废。
+// 验证码错误与密码错误计入同一个用户名的失败次数
+func CompleteMFAChallenge(ctx context.Context, mfaToken, code string, client ClientInfo) (*models.User, SessionInfo, error) {
+	claims, err := jwt.ValidatePurposeToken(mfaToken, purposeMFALogin)
+	if err != nil {
+		return nil, SessionInfo{}, ErrInvalidMFAToken
+	}
+
+	revoked, err := revocation.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, SessionInfo{}, err
+	}
+	if revoked {
+		return nil, SessionInfo{}, ErrInvalidMFAToken
+	}
+
+	user := GetUserByID(claims.Subject)
+	if user == nil || !user.TOTPEnabled {
+		return nil, SessionInfo{}, ErrInvalidMFAToken
+	}
+
+	if err := checkLoginThrottle(ctx, user.Username, client); err != nil {
+		return nil, SessionInfo{}, err
+	}
+	if err := VerifySecondFactor(user, code); err != nil {
+		recordLoginFailure(ctx, user.Username, user.ID, client, "invalid second factor")
+		return nil, SessionInfo{}, err
+	}
+
+	if err := revocation.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		log.Printf("Failed to revoke mfa token %s: %v", claims.ID, err)
+		return nil, SessionInfo{}, err
+	}
+
+	return user, SessionInfo{
+		DeviceID:   claims.Data["device_id"],
+		DeviceName: claims.Data["device_name"],
+		Platform:   claims.Data["platform"],
+	}, nil
+}
+
+// VerifySecondFactor 校验 6 位 TOTP 验证码，其他格式按恢复码处理
+func VerifySecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return verifyTOTP(user, code)
+	}
+	return useRecoveryCode(user.ID, code)
+}
+
+// verifyTOTP 允许前后各一个时间步的时钟偏差，同一时间步的验证码只能使用一次
+func verifyTOTP(user *models.User, code string) error {
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), 1)
+	if !ok {
+		return ErrInvalidOTP
+	}
+
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+func useRecoveryCode(userID, code string) error {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return ErrInvalidOTP
+	}
+
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidOTP
+	}
+
+	log.Printf("Recovery code used: userID=%s", userID)
+	return nil
+}
+
+// replaceRecoveryCodes 删除用户已有的恢复码并生成新的一组，返回明文
+func replaceRecoveryCodes(db *gorm.DB, userID string) ([]string, error) {
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		record := &models.RecoveryCode{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			CodeHash:  hashToken(normalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		}
+		if err := db.Create(record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode 生成形如 abcde-fghjk 的恢复码
+func generateRecoveryCode() (string, error) {
+	code, err := readCode(rand.Reader, recoveryCodeAlphabet, 10)
+	if err != nil {
+		return "", err
+	}
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func isTOTPCode(code string) bool {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/cyperlo/im/pkg/totp"
+)
+
+// setupMFATest 创建一个已开启两步验证的用户，限流计数和 denylist 每个测试独立
+func setupMFATest(t *testing.T) *models.User {
+	t.Helper()
+	setupTokenTest(t)
+	ratelimit.Init(nil)
+	revocation.Init(nil)
+	t.Cleanup(func() {
+		ratelimit.Init(nil)
+		revocation.Init(nil)
+	})
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createTestUser(t, "user-1", "alice")
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return GetUserByID(user.ID)
+}
+
+func totpCode(t *testing.T, user *models.User, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(user.TOTPSecret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// 同一时间步的验证码只能用一次，已经用过更晚的时间步后，更早的验证码也不再接受
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	user := setupMFATest(t)
+
+	code := totpCode(t, user, 0)
+	if err := VerifySecondFactor(user, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := VerifySecondFactor(user, code); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("replay = %v, want ErrInvalidOTP", err)
+	}
+	if err := VerifySecondFactor(user, totpCode(t, user, -1)); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("older step after newer one = %v, want ErrInvalidOTP", err)
+	}
+	if err := VerifySecondFactor(user, totpCode(t, user, 1)); err != nil {
+		t.Fatalf("next step: %v", err)
+	}
+	if err := VerifySecondFactor(user, totpCode(t, user, 3)); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("code outside the skew window = %v, want ErrInvalidOTP", err)
+	}
+}
+
+func TestVerifyTOTPNotEnrolled(t *testing.T) {
+	setupTokenTest(t)
+	user := createTestUser(t, "user-1", "alice")
+	if err := verifyTOTP(user, "123456"); !errors.Is(err, ErrTOTPNotEnrolled) {
+		t.Fatalf("verifyTOTP() = %v, want ErrTOTPNotEnrolled", err)
+	}
+}
+
+// 每个字节值各出现一次时，被接受的字节必须均匀地映射到恢复码字母表
+func TestReadCodeRecoveryAlphabetUniform(t *testing.T) {
+	random := make([]byte, 256)
+	for i := range random {
+		random[i] = byte(i)
+	}
+	accepted := 256 - 256%len(recoveryCodeAlphabet)
+	code, err := readCode(bytes.NewReader(random), recoveryCodeAlphabet, accepted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range recoveryCodeAlphabet {
+		if n := strings.Count(code, string(r)); n != accepted/len(recoveryCodeAlphabet) {
+			t.Errorf("letter %q appeared %d times, want %d", r, n, accepted/len(recoveryCodeAlphabet))
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	user := setupMFATest(t)
+
+	codes, err := replaceRecoveryCodes(database.DB, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[` + recoveryCodeAlphabet + `]{5}-[` + recoveryCodeAlphabet + `]{5}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) || seen[code] {
+			t.Errorf("recovery code %q is malformed or repeated", code)
+		}
+		seen[code] = true
+	}
+
+	// 只保存哈希
+	var stored []models.RecoveryCode
+	database.DB.Where("user_id = ?", user.ID).Find(&stored)
+	for _, record := range stored {
+		if seen[record.CodeHash] || strings.Contains(record.CodeHash, "-") {
+			t.Errorf("recovery code stored in plain text: %q", record.CodeHash)
+		}
+	}
+
+	// 输入时忽略大小写、连字符和空格，每个恢复码只能用一次
+	typed := strings.ToUpper(strings.Replace(codes[0], "-", " ", 1))
+	if err := VerifySecondFactor(user, typed); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if err := VerifySecondFactor(user, codes[0]); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("reused recovery code = %v, want ErrInvalidOTP", err)
+	}
+	for _, code := range []string{"", "-", "aaaaa-aaaaa"} {
+		if err := VerifySecondFactor(user, code); !errors.Is(err, ErrInvalidOTP) {
+			t.Errorf("VerifySecondFactor(%q) = %v, want ErrInvalidOTP", code, err)
+		}
+	}
+
+	// 重新生成后旧的恢复码全部作废
+	fresh, err := replaceRecoveryCodes(database.DB, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySecondFactor(user, codes[1]); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("old recovery code after regeneration = %v, want ErrInvalidOTP", err)
+	}
+	if err := VerifySecondFactor(user, fresh[0]); err != nil {
+		t.Fatalf("new recovery code: %v", err)
+	}
+}
+
+// mfa_token 只能完成一次登录；验证码错误计入用户名的失败次数
+func TestCompleteMFAChallenge(t *testing.T) {
+	user := setupMFATest(t)
+	ctx := context.Background()
+	client := ClientInfo{IP: "198.51.100.7"}
+
+	mfaToken, err := CreateMFAChallenge(user, SessionInfo{DeviceID: "device-1", Platform: "ios"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := CompleteMFAChallenge(ctx, mfaToken, totpCode(t, user, 5), client); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("wrong code = %v, want ErrInvalidOTP", err)
+	}
+	var failures int64
+	database.DB.Model(&models.AuditEvent{}).Where("type = ? AND user_id = ?", AuditLoginFailed, user.ID).Count(&failures)
+	if failures != 1 {
+		t.Errorf("%d login failures recorded, want 1", failures)
+	}
+
+	got, info, err := CompleteMFAChallenge(ctx, mfaToken, totpCode(t, user, 0), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || info.DeviceID != "device-1" || info.Platform != "ios" {
+		t.Errorf("CompleteMFAChallenge() = %s, %+v", got.ID, info)
+	}
+
+	if _, _, err := CompleteMFAChallenge(ctx, mfaToken, totpCode(t, user, 1), client); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("reused mfa token = %v, want ErrInvalidMFAToken", err)
+	}
+	if _, _, err := CompleteMFAChallenge(ctx, "not-a-token", totpCode(t, user, 1), client); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("invalid mfa token = %v, want ErrInvalidMFAToken", err)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/gin-gonic/gin"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RequireUser 只接受 IM 自身签发给用户的 access token，第三方应用的 token 不能管理账号
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少 token"})
+			c.Abort()
+			return
+		}
+
+		claims, err := ValidateAccessToken(c.Request.Context(), token)
+		if err != nil || claims.UserID == "" || claims.ClientID != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// ValidateAccessToken 校验签名和有效期，并检查 token 是否已被吊销
+func ValidateAccessToken(ctx context.Context, token string) (*jwt.Claims, error) {
+	claims, err := jwt.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := revocation.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		log.Printf("Failed to check token revocation: %v", err)
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	revoked, err = revocation.IsSessionRevoked(ctx, claims.SessionID)
+	if err != nil {
+		log.Printf("Failed to check session revocation: %v", err)
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,603 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var authorizationCodeTTL = 5 * time.Minute
+
+// 支持的 scope 及其在授权页上展示的说明
+var supportedScopes = map[string]string{
+	"openid":             "使用 IM 账号登录",
+	"profile":            "读取你的用户名",
+	"email":              "读取你的邮箱地址",
+	"messages:send":      "以你的身份发送消息",
+	"conversations:read": "读取你的会话和消息",
+}
+
+// 应用以自身身份（client_credentials）可申请的 scope
+var appScopes = []string{"messages:send", "conversations:read"}
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+	Username            string `form:"username"`
+	Password            string `form:"password"`
+	OTP                 string `form:"otp"`
+	Action              string `form:"action"`
+}
+
+type RegisterClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	Public       bool     `json:"public"`
+}
+
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>授权 {{.ClientName}}</title>
+</head>
+<body>
+<h2>{{.ClientName}} 请求访问你的 IM 账号</h2>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+{{if .Error}}<p style="color:red">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="hidden" name="response_type" value="code">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<p><input name="username" placeholder="用户名" value="{{.Request.Username}}" autocomplete="username"></p>
+<p><input name="password" type="password" placeholder="密码" autocomplete="current-password"></p>
+<p><input name="otp" placeholder="两步验证码或恢复码（已开启时填写）" autocomplete="one-time-code"></p>
+<button type="submit" name="action" value="approve">同意并登录</button>
+<button type="submit" name="action" value="deny">拒绝</button>
+</form>
+</body>
+</html>`))
+
+// Authorize 校验授权请求并展示登录/授权页
+func Authorize(c *gin.Context) {
+	var req AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	client, ok := validateAuthorizeRequest(c, &req)
+	if !ok {
+		return
+	}
+
+	renderConsent(c, http.StatusOK, client, &req, "")
+}
+
+// Approve 处理授权页提交：登录成功并同意后签发一次性授权码并跳回 redirect_uri
+func Approve(c *gin.Context) {
+	var req AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	client, ok := validateAuthorizeRequest(c, &req)
+	if !ok {
+		return
+	}
+
+	if req.Action != "approve" {
+		redirectWithError(c, &req, oauthError{"access_denied", "the user denied the request"})
+		return
+	}
+
+	clientInfo := ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	user, err := AuthenticatePassword(c.Request.Context(), req.Username, req.Password, clientInfo)
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+		renderConsent(c, http.StatusTooManyRequests, client, &req, fmt.Sprintf("尝试次数过多，请 %d 秒后再试", throttled.RetryAfterSeconds()))
+		return
+	}
+	if errors.Is(err, ErrEmailNotVerified) {
+		renderConsent(c, http.StatusForbidden, client, &req, "邮箱尚未验证，请先完成邮箱验证")
+		return
+	}
+	if err != nil {
+		renderConsent(c, http.StatusUnauthorized, client, &req, "用户名或密码错误")
+		return
+	}
+
+	if user.TOTPEnabled {
+		if err := VerifySecondFactor(user, req.OTP); err != nil {
+			recordLoginFailure(c.Request.Context(), user.Username, user.ID, clientInfo, "invalid second factor")
+			renderConsent(c, http.StatusUnauthorized, client, &req, "两步验证码错误")
+			return
+		}
+	}
+	resetLoginFailures(c.Request.Context(), user.Username)
+
+	code, err := createAuthorizationCode(client.ID, user.ID, &req)
+	if err != nil {
+		log.Printf("Failed to create authorization code: %v", err)
+		redirectWithError(c, &req, oauthError{"server_error", "failed to issue authorization code"})
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	c.Redirect(http.StatusFound, appendQuery(req.RedirectURI, params))
+}
+
+// Token OAuth2 token 端点，支持 authorization_code、refresh_token、client_credentials 和设备授权
+func Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, oerr := authenticateClient(c)
+	if oerr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, *oerr)
+		return
+	}
+
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		exchangeAuthorizationCode(c, client)
+	case "refresh_token":
+		refreshClientToken(c, client)
+	case "client_credentials":
+		issueAppToken(c, client)
+	case deviceCodeGrantType:
+		exchangeDeviceCode(c, client)
+	default:
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"unsupported_grant_type", "grant_type is not supported"})
+	}
+}
+
+// Revoke RFC 7009 token 吊销端点。应用只能吊销签发给自己的 token；
+// 无论 token 是否存在都返回 200，避免泄露 token 的有效性。
+func Revoke(c *gin.Context) {
+	client, oerr := authenticateClient(c)
+	if oerr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, *oerr)
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_request", "token is required"})
+		return
+	}
+
+	if refreshToken := FindRefreshToken(token); refreshToken != nil {
+		if refreshToken.ClientID == client.ID {
+			if err := RevokeTokenFamily(refreshToken.FamilyID); err != nil {
+				writeOAuthError(c, http.StatusServiceUnavailable, oauthError{"server_error", "failed to revoke token"})
+				return
+			}
+		}
+		c.Status(http.StatusOK)
+		return
+	}
+
+	if claims, err := jwt.ValidateToken(token); err == nil && claims.ClientID == client.ID {
+		if err := revocation.RevokeToken(c.Request.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+			writeOAuthError(c, http.StatusServiceUnavailable, oauthError{"server_error", "failed to revoke token"})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func RegisterClient(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req RegisterClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, uri := range req.RedirectURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 redirect_uri: " + uri})
+			return
+		}
+	}
+
+	for _, scope := range req.Scopes {
+		if _, ok := supportedScopes[scope]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的 scope: " + scope})
+			return
+		}
+	}
+
+	client := &models.Client{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		Scopes:       strings.Join(req.Scopes, " "),
+		Public:       req.Public,
+		OwnerID:      userID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	// public 客户端没有 client_secret
+	var secret string
+	if !client.Public {
+		var err error
+		secret, err = generateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+			return
+		}
+
+		secretHash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+			return
+		}
+		client.SecretHash = string(secretHash)
+	}
+
+	if err := database.DB.Create(client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建应用失败"})
+		return
+	}
+
+	// client_secret 只在创建时返回一次
+	c.JSON(http.StatusCreated, gin.H{
+		"client_id":     client.ID,
+		"client_secret": secret,
+		"name":          client.Name,
+		"redirect_uris": req.RedirectURIs,
+		"scopes":        req.Scopes,
+		"public":        client.Public,
+	})
+}
+
+func GetClients(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var clients []models.Client
+	if err := database.DB.Where("owner_id = ?", userID).Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取应用列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+func GetClientByID(clientID string) *models.Client {
+	var client models.Client
+	if err := database.DB.Where("id = ?", clientID).First(&client).Error; err != nil {
+		return nil
+	}
+	return &client
+}
+
+// validateAuthorizeRequest 校验 client 和 redirect_uri，并补全默认的 redirect_uri 与 scope。
+// client 或 redirect_uri 无效时不能跳转，直接返回错误；其余错误跳回 redirect_uri。
+func validateAuthorizeRequest(c *gin.Context, req *AuthorizeRequest) (*models.Client, bool) {
+	client := GetClientByID(req.ClientID)
+	if client == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "unknown client_id"})
+		return nil, false
+	}
+
+	redirectURIs := strings.Fields(client.RedirectURIs)
+	if req.RedirectURI == "" && len(redirectURIs) == 1 {
+		req.RedirectURI = redirectURIs[0]
+	}
+	if !slices.Contains(redirectURIs, req.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "redirect_uri does not match a registered uri"})
+		return nil, false
+	}
+
+	if req.ResponseType != "code" {
+		redirectWithError(c, req, oauthError{"unsupported_response_type", "only response_type=code is supported"})
+		return nil, false
+	}
+
+	if req.Scope == "" {
+		req.Scope = client.Scopes
+	}
+	clientScopes := strings.Fields(client.Scopes)
+	for _, scope := range strings.Fields(req.Scope) {
+		if !slices.Contains(clientScopes, scope) {
+			redirectWithError(c, req, oauthError{"invalid_scope", "scope " + scope + " is not allowed for this client"})
+			return nil, false
+		}
+	}
+	req.Scope = strings.Join(strings.Fields(req.Scope), " ")
+
+	if oerr := validateCodeChallenge(req.CodeChallenge, req.CodeChallengeMethod); oerr != nil {
+		redirectWithError(c, req, *oerr)
+		return nil, false
+	}
+	if client.Public && req.CodeChallenge == "" {
+		redirectWithError(c, req, oauthError{"invalid_request", "public clients must use PKCE"})
+		return nil, false
+	}
+
+	return client, true
+}
+
+func renderConsent(c *gin.Context, status int, client *models.Client, req *AuthorizeRequest, errMsg string) {
+	var scopes []string
+	for _, scope := range strings.Fields(req.Scope) {
+		scopes = append(scopes, supportedScopes[scope])
+	}
+
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := consentTemplate.Execute(c.Writer, gin.H{
+		"ClientName": client.Name,
+		"Scopes":     scopes,
+		"Request":    req,
+		"Error":      errMsg,
+	}); err != nil {
+		log.Printf("Failed to render consent page: %v", err)
+	}
+}
+
+func redirectWithError(c *gin.Context, req *AuthorizeRequest, oerr oauthError) {
+	params := url.Values{"error": {oerr.Code}, "error_description": {oerr.Description}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	c.Redirect(http.StatusFound, appendQuery(req.RedirectURI, params))
+}
+
+func createAuthorizationCode(clientID, userID string, req *AuthorizeRequest) (string, error) {
+	code, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	authCode := &models.AuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            time.Now(),
+		ExpiresAt:           time.Now().Add(authorizationCodeTTL),
+		CreatedAt:           time.Now(),
+	}
+
+	if err := database.DB.Create(authCode).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// authenticateClient 支持 HTTP Basic 和表单两种方式传递 client 凭证，
+// public 客户端只需提供 client_id，由 PKCE 保证授权码不被冒用
+func authenticateClient(c *gin.Context) (*models.Client, *oauthError) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	client := GetClientByID(clientID)
+	if client == nil {
+		return nil, &oauthError{"invalid_client", "client authentication failed"}
+	}
+
+	if client.Public {
+		return client, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)); err != nil {
+		return nil, &oauthError{"invalid_client", "client authentication failed"}
+	}
+
+	return client, nil
+}
+
+func exchangeAuthorizationCode(c *gin.Context, client *models.Client) {
+	codeHash := hashToken(c.PostForm("code"))
+
+	var authCode models.AuthorizationCode
+	if err := database.DB.Where("code_hash = ?", codeHash).First(&authCode).Error; err != nil || authCode.ClientID != client.ID {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "authorization code is invalid"})
+		return
+	}
+
+	// 授权码只能使用一次，重复使用时吊销之前用它换到的 token
+	result := database.DB.Model(&models.AuthorizationCode{}).
+		Where("code_hash = ? AND used_at IS NULL", codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to redeem authorization code"})
+		return
+	}
+	if result.RowsAffected != 1 {
+		log.Printf("Authorization code reused: clientID=%s, userID=%s", authCode.ClientID, authCode.UserID)
+		if authCode.FamilyID != "" {
+			if err := RevokeTokenFamily(authCode.FamilyID); err != nil {
+				log.Printf("Failed to revoke token family %s: %v", authCode.FamilyID, err)
+			}
+		}
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "authorization code has already been used"})
+		return
+	}
+
+	if time.Now().After(authCode.ExpiresAt) {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "authorization code has expired"})
+		return
+	}
+
+	if c.PostForm("redirect_uri") != authCode.RedirectURI {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "redirect_uri does not match"})
+		return
+	}
+
+	verifier := c.PostForm("code_verifier")
+	if authCode.CodeChallenge != "" && !verifyCodeVerifier(authCode.CodeChallenge, verifier) {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "code_verifier is invalid"})
+		return
+	}
+	if authCode.CodeChallenge == "" && verifier != "" {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "code_verifier was not expected"})
+		return
+	}
+
+	// 授权码记下换到的 family，重复使用时才能吊销；记录失败则 token 一并回滚
+	var tokens *TokenPair
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		tokens, err = issueTokenPair(tx, models.RefreshToken{UserID: authCode.UserID, ClientID: client.ID, Scope: authCode.Scope})
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AuthorizationCode{}).Where("code_hash = ?", codeHash).Update("family_id", tokens.FamilyID).Error
+	})
+	if err == nil {
+		tokens.IDToken, err = buildIDToken(tokens, authCode.Nonce, authCode.AuthTime)
+	}
+	if err != nil {
+		log.Printf("Failed to issue tokens for authorization code: clientID=%s, userID=%s, err=%v", client.ID, authCode.UserID, err)
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue token"})
+		return
+	}
+
+	writeTokenResponse(c, tokens)
+}
+
+func refreshClientToken(c *gin.Context, client *models.Client) {
+	tokens, err := RotateRefreshToken(c.PostForm("refresh_token"), client.ID, "")
+	if err != nil {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_grant", "refresh token is invalid or expired"})
+		return
+	}
+
+	if tokens.IDToken, err = buildIDToken(tokens, "", time.Time{}); err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue token"})
+		return
+	}
+
+	writeTokenResponse(c, tokens)
+}
+
+// issueAppToken 为服务端应用签发不代表任何用户的 app token，不附带 refresh token
+func issueAppToken(c *gin.Context, client *models.Client) {
+	if client.Public {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"unauthorized_client", "public clients cannot use client_credentials"})
+		return
+	}
+
+	var allowed []string
+	for _, scope := range strings.Fields(client.Scopes) {
+		if slices.Contains(appScopes, scope) {
+			allowed = append(allowed, scope)
+		}
+	}
+
+	requested := strings.Fields(c.PostForm("scope"))
+	if len(requested) == 0 {
+		requested = allowed
+	}
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_scope", "scope " + scope + " is not allowed for this client"})
+			return
+		}
+	}
+	if len(requested) == 0 {
+		writeOAuthError(c, http.StatusBadRequest, oauthError{"invalid_scope", "client has no scopes usable by an app token"})
+		return
+	}
+
+	scope := strings.Join(requested, " ")
+	accessToken, err := jwt.GenerateClientToken("", client.ID, scope)
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, oauthError{"server_error", "failed to issue token"})
+		return
+	}
+
+	writeTokenResponse(c, &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(jwt.AccessTokenTTL().Seconds()),
+		Scope:       scope,
+	})
+}
+
+func writeTokenResponse(c *gin.Context, tokens *TokenPair) {
+	resp := gin.H{
+		"access_token": tokens.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   tokens.ExpiresIn,
+		"scope":        tokens.Scope,
+	}
+	if tokens.RefreshToken != "" {
+		resp["refresh_token"] = tokens.RefreshToken
+	}
+	if tokens.IDToken != "" {
+		resp["id_token"] = tokens.IDToken
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func writeOAuthError(c *gin.Context, status int, oerr oauthError) {
+	c.JSON(status, gin.H{"error": oerr.Code, "error_description": oerr.Description})
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
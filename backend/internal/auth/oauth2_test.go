@@ -0,0 +1,405 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+// createTestClient 注册一个应用，confidential 应用使用 secret 作为 client_secret
+func createTestClient(t *testing.T, id, secret, scopes string) *models.Client {
+	t.Helper()
+	client := &models.Client{
+		ID:           id,
+		Name:         id,
+		RedirectURIs: testRedirectURI,
+		Scopes:       scopes,
+		Public:       secret == "",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if secret != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SecretHash = string(hash)
+	}
+	if err := database.DB.Create(client).Error; err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func createTestUser(t *testing.T, id, username string) *models.User {
+	t.Helper()
+	user := &models.User{
+		ID:        id,
+		Username:  username,
+		Email:     username + "@example.com",
+		Status:    models.UserStatusActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	IDToken          string `json:"id_token"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// postToken 向 token 端点提交表单；basic 非空时以 HTTP Basic 传递 client 凭证
+func postToken(t *testing.T, form url.Values, basic ...string) (int, tokenResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/token", Token)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(basic) == 2 {
+		req.SetBasicAuth(basic[0], basic[1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp tokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("token response %s: %v", w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestTokenClientAuthentication(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "s3cret:/+&", "openid")
+	createTestClient(t, "spa", "", "openid")
+
+	tests := []struct {
+		name  string
+		form  url.Values
+		basic []string
+		ok    bool
+	}{
+		{name: "basic", basic: []string{"web", url.QueryEscape("s3cret:/+&")}, ok: true},
+		{name: "form", form: url.Values{"client_id": {"web"}, "client_secret": {"s3cret:/+&"}}, ok: true},
+		{name: "public client without secret", form: url.Values{"client_id": {"spa"}}, ok: true},
+		{name: "wrong secret", basic: []string{"web", "wrong"}},
+		{name: "secret not url-encoded in basic", basic: []string{"web", "s3cret:/+&"}},
+		{name: "missing secret", form: url.Values{"client_id": {"web"}}},
+		{name: "unknown client", form: url.Values{"client_id": {"nobody"}, "client_secret": {"s3cret:/+&"}}},
+		{name: "no credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"grant_type": {"password"}}
+			for k, v := range tt.form {
+				form[k] = v
+			}
+
+			// 认证通过后才会检查 grant_type
+			status, resp := postToken(t, form, tt.basic...)
+			if tt.ok && (status != http.StatusBadRequest || resp.Error != "unsupported_grant_type") {
+				t.Fatalf("status = %d, error = %q, want unsupported_grant_type", status, resp.Error)
+			}
+			if !tt.ok && (status != http.StatusUnauthorized || resp.Error != "invalid_client") {
+				t.Fatalf("status = %d, error = %q, want invalid_client", status, resp.Error)
+			}
+		})
+	}
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "secret", "openid profile")
+	createTestClient(t, "other", "secret", "openid profile")
+	createTestUser(t, "user-1", "alice")
+
+	code, err := createAuthorizationCode("web", "user-1", &AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "openid profile", Nonce: "n-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {testRedirectURI}}
+
+	if status, resp := postToken(t, form, "other", "secret"); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+		t.Fatalf("code redeemed by another client: %d %+v", status, resp)
+	}
+
+	status, resp := postToken(t, form, "web", "secret")
+	if status != http.StatusOK || resp.AccessToken == "" || resp.RefreshToken == "" || resp.IDToken == "" {
+		t.Fatalf("exchange = %d %+v", status, resp)
+	}
+	claims, err := jwt.ValidateToken(resp.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "user-1" || claims.ClientID != "web" || claims.Scope != "openid profile" {
+		t.Errorf("access token claims = %+v", claims)
+	}
+
+	// 授权码被重复使用时吊销第一次换到的 token
+	if status, resp := postToken(t, form, "web", "secret"); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+		t.Fatalf("second exchange = %d %+v", status, resp)
+	}
+	if _, err := RotateRefreshToken(resp.RefreshToken, "web", ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh token after code reuse = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+// 记录授权码对应的 family 失败时不能返回 token，否则授权码被重用时无法吊销这些 token
+func TestExchangeAuthorizationCodeFamilyUpdateFails(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "secret", "openid")
+	createTestUser(t, "user-1", "alice")
+
+	code, err := createAuthorizationCode("web", "user-1", &AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.DB.Callback().Update().Before("gorm:update").Register("test:fail_family_id", func(db *gorm.DB) {
+		if updates, ok := db.Statement.Dest.(map[string]interface{}); ok && db.Statement.Table == "oauth_authorization_codes" {
+			if _, ok := updates["family_id"]; ok {
+				db.AddError(errors.New("database unavailable"))
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {testRedirectURI}}
+	if status, resp := postToken(t, form, "web", "secret"); status != http.StatusInternalServerError || resp.Error != "server_error" || resp.RefreshToken != "" {
+		t.Fatalf("exchange = %d %+v, want server_error", status, resp)
+	}
+	var issued int64
+	database.DB.Model(&models.RefreshToken{}).Where("client_id = ?", "web").Count(&issued)
+	if issued != 0 {
+		t.Errorf("%d refresh tokens left after failed exchange, want 0", issued)
+	}
+}
+
+func TestExchangeAuthorizationCodeRejects(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "secret", "openid")
+	createTestUser(t, "user-1", "alice")
+
+	tests := []struct {
+		name    string
+		prepare func(code string)
+		form    url.Values
+	}{
+		{name: "unknown code", form: url.Values{"code": {"not-a-code"}}},
+		{name: "redirect_uri mismatch", form: url.Values{"redirect_uri": {"https://evil.example.com/callback"}}},
+		{
+			name: "expired",
+			prepare: func(code string) {
+				database.DB.Model(&models.AuthorizationCode{}).Where("code_hash = ?", hashToken(code)).
+					Update("expires_at", time.Now().Add(-time.Minute))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := createAuthorizationCode("web", "user-1", &AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "openid"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(code)
+			}
+
+			form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {testRedirectURI}}
+			for k, v := range tt.form {
+				form[k] = v
+			}
+			if status, resp := postToken(t, form, "web", "secret"); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+				t.Fatalf("exchange = %d %+v, want invalid_grant", status, resp)
+			}
+		})
+	}
+}
+
+func TestRefreshClientTokenIsBoundToClient(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "secret", "profile")
+	createTestClient(t, "other", "secret", "profile")
+
+	pair, err := IssueClientTokenPair("user-1", "web", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {pair.RefreshToken}}
+
+	if status, resp := postToken(t, form, "other", "secret"); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+		t.Fatalf("refresh by another client = %d %+v", status, resp)
+	}
+	if status, resp := postToken(t, form, "web", "secret"); status != http.StatusOK || resp.RefreshToken == "" || resp.Scope != "profile" {
+		t.Fatalf("refresh = %d %+v", status, resp)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "bot", "secret", "openid messages:send conversations:read")
+	createTestClient(t, "login-only", "secret", "openid profile")
+	createTestClient(t, "spa", "", "messages:send")
+
+	tests := []struct {
+		name      string
+		client    []string
+		scope     string
+		wantScope string
+		wantErr   string
+	}{
+		{name: "defaults to every app scope", client: []string{"bot", "secret"}, wantScope: "messages:send conversations:read"},
+		{name: "subset", client: []string{"bot", "secret"}, scope: "messages:send", wantScope: "messages:send"},
+		{name: "user-only scope", client: []string{"bot", "secret"}, scope: "openid", wantErr: "invalid_scope"},
+		{name: "unregistered scope", client: []string{"login-only", "secret"}, scope: "messages:send", wantErr: "invalid_scope"},
+		{name: "no app scopes", client: []string{"login-only", "secret"}, wantErr: "invalid_scope"},
+		{name: "public client", client: []string{"spa", ""}, wantErr: "unauthorized_client"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"grant_type": {"client_credentials"}, "client_id": {tt.client[0]}, "client_secret": {tt.client[1]}}
+			if tt.scope != "" {
+				form.Set("scope", tt.scope)
+			}
+
+			status, resp := postToken(t, form)
+			if tt.wantErr != "" {
+				if status != http.StatusBadRequest || resp.Error != tt.wantErr {
+					t.Fatalf("token = %d %+v, want %s", status, resp, tt.wantErr)
+				}
+				return
+			}
+			if status != http.StatusOK || resp.Scope != tt.wantScope {
+				t.Fatalf("token = %d %+v, want scope %q", status, resp, tt.wantScope)
+			}
+			// app token 不代表用户，也不附带 refresh token
+			if resp.RefreshToken != "" || resp.IDToken != "" {
+				t.Errorf("app token response carries user tokens: %+v", resp)
+			}
+			claims, err := jwt.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !claims.IsApp() || claims.UserID != "" || claims.ClientID != tt.client[0] || claims.Subject != tt.client[0] {
+				t.Errorf("app token claims = %+v", claims)
+			}
+		})
+	}
+}
+
+// RFC 7009：应用只能吊销签发给自己的 token，其余情况也返回 200
+func TestRevokeEndpoint(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "web", "secret", "profile messages:send")
+	createTestClient(t, "other", "secret", "profile messages:send")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/revoke", Revoke)
+	revoke := func(token, clientID string) int {
+		form := url.Values{"token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/revoke", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, "secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	ctx := context.Background()
+
+	pair, err := IssueClientTokenPair("user-1", "web", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := revoke(pair.AccessToken, "other"); status != http.StatusOK {
+		t.Fatalf("revoke by another client = %d", status)
+	}
+	if status := revoke(pair.RefreshToken, "other"); status != http.StatusOK {
+		t.Fatalf("revoke by another client = %d", status)
+	}
+	if _, err := ValidateAccessToken(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("another client revoked the access token: %v", err)
+	}
+	if FindRefreshToken(pair.RefreshToken).RevokedAt != nil {
+		t.Fatal("another client revoked the refresh token")
+	}
+
+	if status := revoke(pair.AccessToken, "web"); status != http.StatusOK {
+		t.Fatalf("revoke access token = %d", status)
+	}
+	if _, err := ValidateAccessToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("revoked access token = %v, want ErrTokenRevoked", err)
+	}
+
+	if status := revoke(pair.RefreshToken, "web"); status != http.StatusOK {
+		t.Fatalf("revoke refresh token = %d", status)
+	}
+	if _, err := RotateRefreshToken(pair.RefreshToken, "web", ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("revoked refresh token = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if status := revoke("not-a-token", "web"); status != http.StatusOK {
+		t.Fatalf("revoke unknown token = %d", status)
+	}
+	if status := revoke("", "web"); status != http.StatusBadRequest {
+		t.Fatalf("revoke without token = %d", status)
+	}
+}
+
+// 吊销会话后，该会话签发的所有 access token 都失效
+func TestValidateAccessTokenRevokedSession(t *testing.T) {
+	setupTokenTest(t)
+	revocation.Init(nil)
+	t.Cleanup(func() { revocation.Init(nil) })
+	ctx := context.Background()
+
+	token, err := jwt.GenerateAccessToken(jwt.Claims{UserID: "user-1", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := jwt.GenerateAccessToken(jwt.Claims{UserID: "user-1", SessionID: "session-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := revokeSession(ctx, &models.Session{ID: "session-1", UserID: "user-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateAccessToken(ctx, token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("token of revoked session = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := ValidateAccessToken(ctx, other); err != nil {
+		t.Fatalf("token of another session = %v", err)
+	}
+}
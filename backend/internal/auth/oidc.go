@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// buildIDToken 在 scope 包含 openid 时签发 ID token，否则返回空字符串
+func buildIDToken(tokens *TokenPair, nonce string, authTime time.Time) (string, error) {
+	scopes := strings.Fields(tokens.Scope)
+	if !slices.Contains(scopes, "openid") {
+		return "", nil
+	}
+
+	user := GetUserByID(tokens.UserID)
+	if user == nil {
+		return "", errors.New("user not found")
+	}
+
+	claims := jwt.IDTokenClaims{Nonce: nonce}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+	if slices.Contains(scopes, "profile") {
+		claims.PreferredUsername = user.Username
+	}
+	if slices.Contains(scopes, "email") {
+		claims.Email = user.Email
+	}
+
+	return jwt.GenerateIDToken(user.ID, tokens.ClientID, claims)
+}
+
+// UserInfo OIDC userinfo 端点，按 access token 的 scope 返回用户声明。
+// IM 自身签发的用户 token 不受 scope 限制。
+func UserInfo(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	claims, err := ValidateAccessToken(c.Request.Context(), token)
+	if token == "" || err != nil || claims.UserID == "" {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	firstParty := claims.ClientID == ""
+	if !firstParty && !claims.HasScopes("openid") {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+		return
+	}
+
+	user := GetUserByID(claims.UserID)
+	if user == nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userClaims(user, firstParty || claims.HasScopes("profile"), firstParty || claims.HasScopes("email")))
+}
+
+func userClaims(user *models.User, profile, email bool) gin.H {
+	resp := gin.H{"sub": user.ID}
+	if profile {
+		resp["preferred_username"] = user.Username
+		resp["updated_at"] = user.UpdatedAt.Unix()
+	}
+	if email {
+		resp["email"] = user.Email
+	}
+	return resp
+}
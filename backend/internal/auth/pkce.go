@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const codeChallengeMethodS256 = "S256"
+
+// RFC 7636: code_verifier 和 code_challenge 都只能由 unreserved 字符组成，长度 43~128
+var pkcePattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// validateCodeChallenge 只接受 S256，不支持 plain
+func validateCodeChallenge(challenge, method string) *oauthError {
+	if challenge == "" {
+		if method != "" {
+			return &oauthError{"invalid_request", "code_challenge_method without code_challenge"}
+		}
+		return nil
+	}
+	if method != codeChallengeMethodS256 {
+		return &oauthError{"invalid_request", "only code_challenge_method=S256 is supported"}
+	}
+	if !pkcePattern.MatchString(challenge) {
+		return &oauthError{"invalid_request", "code_challenge is malformed"}
+	}
+	return nil
+}
+
+func verifyCodeVerifier(challenge, verifier string) bool {
+	if !pkcePattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
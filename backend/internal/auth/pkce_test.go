@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RFC 7636 附录 B 的示例
+const (
+	rfcCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestValidateCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		ok        bool
+	}{
+		{name: "no pkce", ok: true},
+		{name: "S256", challenge: rfcCodeChallenge, method: "S256", ok: true},
+		{name: "plain", challenge: rfcCodeChallenge, method: "plain"},
+		{name: "missing method", challenge: rfcCodeChallenge},
+		{name: "lowercase method", challenge: rfcCodeChallenge, method: "s256"},
+		{name: "method without challenge", method: "S256"},
+		{name: "too short", challenge: rfcCodeChallenge[:42], method: "S256"},
+		{name: "too long", challenge: strings.Repeat("a", 129), method: "S256"},
+		{name: "reserved characters", challenge: rfcCodeChallenge[:42] + "+", method: "S256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oerr := validateCodeChallenge(tt.challenge, tt.method)
+			if tt.ok && oerr != nil {
+				t.Fatalf("validateCodeChallenge() = %+v", oerr)
+			}
+			if !tt.ok && (oerr == nil || oerr.Code != "invalid_request") {
+				t.Fatalf("validateCodeChallenge() = %+v, want invalid_request", oerr)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	if !verifyCodeVerifier(rfcCodeChallenge, rfcCodeVerifier) {
+		t.Fatal("RFC 7636 example verifier rejected")
+	}
+	for _, verifier := range []string{
+		"",
+		rfcCodeVerifier[:42],
+		rfcCodeVerifier + "x",
+		strings.ToUpper(rfcCodeVerifier),
+		// plain 方式：把 challenge 本身当作 verifier
+		rfcCodeChallenge,
+	} {
+		if verifyCodeVerifier(rfcCodeChallenge, verifier) {
+			t.Errorf("verifyCodeVerifier(%q) = true", verifier)
+		}
+	}
+}
+
+func TestExchangeAuthorizationCodePKCE(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "spa", "", "profile")
+
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		ok        bool
+	}{
+		{name: "matching verifier", challenge: rfcCodeChallenge, verifier: rfcCodeVerifier, ok: true},
+		{name: "missing verifier", challenge: rfcCodeChallenge},
+		{name: "wrong verifier", challenge: rfcCodeChallenge, verifier: strings.Repeat("a", 43)},
+		{name: "verifier without challenge", verifier: rfcCodeVerifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "profile", CodeChallenge: tt.challenge}
+			if tt.challenge != "" {
+				req.CodeChallengeMethod = codeChallengeMethodS256
+			}
+			code, err := createAuthorizationCode("spa", "user-1", req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, resp := postToken(t, url.Values{
+				"grant_type":    {"authorization_code"},
+				"client_id":     {"spa"},
+				"code":          {code},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {tt.verifier},
+			})
+			if tt.ok && (status != http.StatusOK || resp.AccessToken == "") {
+				t.Fatalf("exchange = %d %+v", status, resp)
+			}
+			if !tt.ok && (status != http.StatusBadRequest || resp.Error != "invalid_grant") {
+				t.Fatalf("exchange = %d %+v, want invalid_grant", status, resp)
+			}
+		})
+	}
+}
+
+// public 应用没有 client_secret，授权请求必须带 S256 的 code_challenge
+func TestAuthorizeRequiresPKCEForPublicClients(t *testing.T) {
+	setupTokenTest(t)
+	createTestClient(t, "spa", "", "profile")
+	createTestClient(t, "web", "secret", "profile")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/authorize", Authorize)
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		errMsg string
+	}{
+		{name: "public with pkce", query: url.Values{"client_id": {"spa"}, "code_challenge": {rfcCodeChallenge}, "code_challenge_method": {"S256"}}},
+		{name: "public without pkce", query: url.Values{"client_id": {"spa"}}, errMsg: "public clients must use PKCE"},
+		{name: "public with plain", query: url.Values{"client_id": {"spa"}, "code_challenge": {rfcCodeChallenge}, "code_challenge_method": {"plain"}}, errMsg: "only code_challenge_method=S256 is supported"},
+		{name: "confidential without pkce", query: url.Values{"client_id": {"web"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Set("response_type", "code")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+tt.query.Encode(), nil))
+
+			if tt.errMsg == "" {
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			location, err := url.Parse(w.Header().Get("Location"))
+			if w.Code != http.StatusFound || err != nil {
+				t.Fatalf("status = %d, Location = %q", w.Code, w.Header().Get("Location"))
+			}
+			if got := location.Query(); got.Get("error") != "invalid_request" || got.Get("error_description") != tt.errMsg {
+				t.Fatalf("redirect error = %v", got)
+			}
+		})
+	}
+}
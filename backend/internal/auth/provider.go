@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const ProviderLocal = "local"
+
+// Identity 身份源认证通过后返回的用户信息
+type Identity struct {
+	Provider string
+	Username string
+	Email    string
+}
+
+// IdentityProvider 校验用户名和密码的身份源。用户不存在或密码错误时返回 ErrInvalidCredentials，
+// 认证链会继续尝试下一个身份源；其他错误（如目录服务不可达）会被记录后同样跳过
+type IdentityProvider interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// IdentityConfig Providers 为按顺序尝试的身份源名称，如 local,ldap
+type IdentityConfig struct {
+	Providers []string
+	LDAP      LDAPConfig
+}
+
+var identityProviders = []IdentityProvider{&LocalProvider{}}
+
+// InitIdentityProviders 按配置组装认证链
+func InitIdentityProviders(config IdentityConfig) error {
+	var providers []IdentityProvider
+	for _, name := range config.Providers {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case ProviderLocal:
+			providers = append(providers, &LocalProvider{})
+		case ProviderLDAP:
+			provider, err := NewLDAPProvider(config.LDAP)
+			if err != nil {
+				return err
+			}
+			providers = append(providers, provider)
+		default:
+			return fmt.Errorf("unknown identity provider %q", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return errors.New("no identity providers configured")
+	}
+
+	identityProviders = providers
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name())
+	}
+	log.Printf("Identity providers: %s", strings.Join(names, ", "))
+	return nil
+}
+
+// authenticateIdentity 依次尝试认证链中的身份源，返回第一个认证成功的用户，
+// 外部身份源的用户首次登录时自动创建本地账号
+func authenticateIdentity(ctx context.Context, username, password string) (*models.User, error) {
+	for _, provider := range identityProviders {
+		identity, err := provider.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Identity provider %s failed: %v", provider.Name(), err)
+			continue
+		}
+
+		user, err := resolveIdentity(identity)
+		if err != nil {
+			log.Printf("Failed to resolve %s identity %s: %v", identity.Provider, identity.Username, err)
+			continue
+		}
+		return user, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// verifyUserPassword 通过用户所属的身份源校验密码，用于关闭两步验证等敏感操作的二次确认
+func verifyUserPassword(ctx context.Context, user *models.User, password string) error {
+	for _, provider := range identityProviders {
+		if provider.Name() != userProvider(user) {
+			continue
+		}
+		if _, err := provider.Authenticate(ctx, user.Username, password); err != nil {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+	return ErrInvalidCredentials
+}
+
+// resolveIdentity 把身份源返回的用户对应到本地账号。同名账号属于其他身份源时拒绝登录，
+// 避免目录中的同名用户接管本地账号
+func resolveIdentity(identity *Identity) (*models.User, error) {
+	user := GetUserByUsername(identity.Username)
+	if user != nil {
+		if userProvider(user) != identity.Provider {
+			return nil, fmt.Errorf("username %s belongs to provider %s", identity.Username, userProvider(user))
+		}
+		if identity.Email != "" && user.Email != identity.Email {
+			database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+				Updates(map[string]interface{}{"email": identity.Email, "updated_at": time.Now()})
+			user.Email = identity.Email
+		}
+		return user, nil
+	}
+
+	return provisionUser(identity)
+}
+
+// provisionUser 为外部身份源的用户创建本地账号。密码由身份源管理，本地不保存密码哈希；
+// 邮箱由目录提供，视为已验证
+func provisionUser(identity *Identity) (*models.User, error) {
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Username:     identity.Username,
+		Email:        identity.Email,
+		Status:       models.UserStatusActive,
+		AuthProvider: identity.Provider,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := database.DB.Create(user).Error; err != nil {
+		// 并发的首次登录可能已经创建了该用户
+		if existing := GetUserByUsername(identity.Username); existing != nil && userProvider(existing) == identity.Provider {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	log.Printf("Provisioned user %s (%s) from %s", user.Username, user.ID, identity.Provider)
+	return user, nil
+}
+
+func userProvider(user *models.User) string {
+	if user.AuthProvider == "" {
+		return ProviderLocal
+	}
+	return user.AuthProvider
+}
+
+// LocalProvider 使用本地数据库中的 bcrypt 密码哈希认证
+type LocalProvider struct{}
+
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+func (p *LocalProvider) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	user := GetUserByUsername(username)
+	if user == nil || userProvider(user) != ProviderLocal || user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{Provider: ProviderLocal, Username: user.Username, Email: user.Email}, nil
+}
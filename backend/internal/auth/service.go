@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var refreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// AuthenticatePassword 通过认证链校验用户名和密码，失败时不区分用户不存在和密码错误。
+// 用户名或 IP 失败次数过多时返回 *ThrottledError，不再校验密码。
+// 成功后失败计数并不清空，调用方在整个登录（包括两步验证）完成后调用 resetLoginFailures
+func AuthenticatePassword(ctx context.Context, username, password string, client ClientInfo) (*models.User, error) {
+	if err := checkLoginThrottle(ctx, username, client); err != nil {
+		return nil, err
+	}
+
+	user, err := authenticateIdentity(ctx, username, password)
+	if err != nil {
+		var userID string
+		if existing := GetUserByUsername(username); existing != nil {
+			userID = existing.ID
+		}
+		recordLoginFailure(ctx, username, userID, client, "invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	// 密码正确之后才提示邮箱未验证，不会泄露账号是否存在
+	if user.Status == models.UserStatusPending {
+		RecordAuditEvent(models.AuditEvent{
+			Type:      AuditLoginFailed,
+			UserID:    user.ID,
+			Username:  user.Username,
+			IP:        client.IP,
+			UserAgent: client.UserAgent,
+			Detail:    "email not verified",
+		})
+		return user, ErrEmailNotVerified
+	}
+
+	return user, nil
+}
+
+type TokenPair struct {
+	UserID       string
+	ClientID     string
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+	DeviceID     string
+	SessionID    string
+	Scope        string
+	FamilyID     string
+	IDToken      string
+}
+
+// IssueTokenPair 为新登录的会话签发 access token 和一个新 family 的 refresh token
+func IssueTokenPair(session *models.Session) (*TokenPair, error) {
+	return issueTokenPair(database.DB, models.RefreshToken{UserID: session.UserID, DeviceID: session.DeviceID, SessionID: session.ID})
+}
+
+// IssueClientTokenPair 为第三方应用签发代表用户的 token 对
+func IssueClientTokenPair(userID, clientID, scope string) (*TokenPair, error) {
+	return issueTokenPair(database.DB, models.RefreshToken{UserID: userID, ClientID: clientID, Scope: scope})
+}
+
+// RotateRefreshToken 用旧 refresh token 换取新的 token 对，旧 token 立即作废。
+// 已被轮换掉的 token 再次出现时视为泄露，整个 family 会被吊销。
+func RotateRefreshToken(rawToken, clientID, deviceID string) (*TokenPair, error) {
+	var current models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", hashToken(rawToken)).First(&current).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 被轮换掉的 token 有 replaced_by；随会话或登出一起吊销的 token 没有，只是单纯失效
+	if current.RevokedAt != nil {
+		if current.ReplacedBy == "" {
+			return nil, ErrInvalidRefreshToken
+		}
+		revokeReusedFamily(&current)
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) || current.ClientID != clientID || current.DeviceID != deviceID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return rotateRefreshToken(current)
+}
+
+// rotateRefreshToken 为 current 签发替代它的 token。并发请求中没有抢到轮换的一方只是失败，
+// 不能当作重用吊销 family，否则客户端的正常重试会把刚换到的 token 一起作废
+func rotateRefreshToken(current models.RefreshToken) (*TokenPair, error) {
+	var replacement *models.RefreshToken
+	var newToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		replacement, newToken, err = createRefreshToken(tx, current)
+		if err != nil {
+			return err
+		}
+
+		// 条件更新保证并发请求中只有一个能完成轮换
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": replacement.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrInvalidRefreshToken
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTokenPair(replacement, newToken)
+}
+
+func FindRefreshToken(rawToken string) *models.RefreshToken {
+	var token models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", hashToken(rawToken)).First(&token).Error; err != nil {
+		return nil
+	}
+	return &token
+}
+
+// RevokeTokenFamily 吊销同一次登录派生出的所有 refresh token
+func RevokeTokenFamily(familyID string) error {
+	return database.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func revokeReusedFamily(token *models.RefreshToken) {
+	log.Printf("Refresh token reuse detected: userID=%s, familyID=%s", token.UserID, token.FamilyID)
+	if err := RevokeTokenFamily(token.FamilyID); err != nil {
+		log.Printf("Failed to revoke token family %s: %v", token.FamilyID, err)
+	}
+}
+
+func issueTokenPair(db *gorm.DB, grant models.RefreshToken) (*TokenPair, error) {
+	grant.FamilyID = uuid.New().String()
+	token, rawToken, err := createRefreshToken(db, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTokenPair(token, rawToken)
+}
+
+// createRefreshToken 按 grant 中的用户、应用、设备和 scope 生成一个新的 refresh token
+func createRefreshToken(db *gorm.DB, grant models.RefreshToken) (*models.RefreshToken, string, error) {
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		UserID:    grant.UserID,
+		FamilyID:  grant.FamilyID,
+		TokenHash: hashToken(rawToken),
+		DeviceID:  grant.DeviceID,
+		SessionID: grant.SessionID,
+		ClientID:  grant.ClientID,
+		Scope:     grant.Scope,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := db.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, rawToken, nil
+}
+
+func buildTokenPair(token *models.RefreshToken, rawToken string) (*TokenPair, error) {
+	accessToken, err := jwt.GenerateAccessToken(jwt.Claims{
+		UserID:    token.UserID,
+		ClientID:  token.ClientID,
+		Scope:     token.Scope,
+		SessionID: token.SessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:       token.UserID,
+		ClientID:     token.ClientID,
+		AccessToken:  accessToken,
+		RefreshToken: rawToken,
+		ExpiresIn:    int64(jwt.AccessTokenTTL().Seconds()),
+		DeviceID:     token.DeviceID,
+		SessionID:    token.SessionID,
+		Scope:        token.Scope,
+		FamilyID:     token.FamilyID,
+	}, nil
+}
+
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/database/dbtest"
+	"github.com/cyperlo/im/pkg/jwt"
+)
+
+// setupTokenTest 准备数据库和 HS256 签名密钥
+func setupTokenTest(t *testing.T) {
+	t.Helper()
+	dbtest.Setup(t)
+	if err := jwt.Init(jwt.Config{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func familyTokens(t *testing.T, familyID string) []models.RefreshToken {
+	t.Helper()
+	var tokens []models.RefreshToken
+	if err := database.DB.Where("family_id = ?", familyID).Order("created_at").Find(&tokens).Error; err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	setupTokenTest(t)
+
+	first, err := IssueTokenPair(&models.Session{ID: "session-1", UserID: "user-1", DeviceID: "device-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := RotateRefreshToken(first.RefreshToken, "", "device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.FamilyID != first.FamilyID || second.SessionID != "session-1" {
+		t.Fatalf("rotated pair = %+v, first = %+v", second, first)
+	}
+
+	claims, err := jwt.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "user-1" || claims.SessionID != "session-1" {
+		t.Errorf("access token claims = %+v", claims)
+	}
+
+	tokens := familyTokens(t, first.FamilyID)
+	if len(tokens) != 2 || tokens[0].RevokedAt == nil || tokens[0].ReplacedBy != tokens[1].ID || tokens[1].RevokedAt != nil {
+		t.Fatalf("family after rotation = %+v", tokens)
+	}
+
+	third, err := RotateRefreshToken(second.RefreshToken, "", "device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.FamilyID != first.FamilyID {
+		t.Errorf("third rotation family = %s, want %s", third.FamilyID, first.FamilyID)
+	}
+}
+
+// 已被轮换掉的 token 再次使用说明它被泄露，整个 family（包括最新的 token）都要作废
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	setupTokenTest(t)
+
+	first, err := IssueTokenPair(&models.Session{ID: "session-1", UserID: "user-1", DeviceID: "device-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := RotateRefreshToken(first.RefreshToken, "", "device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := IssueTokenPair(&models.Session{ID: "session-2", UserID: "user-1", DeviceID: "device-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RotateRefreshToken(first.RefreshToken, "", "device-1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse error = %v, want ErrRefreshTokenReused", err)
+	}
+	for _, token := range familyTokens(t, first.FamilyID) {
+		if token.RevokedAt == nil {
+			t.Errorf("token %s still active after reuse", token.ID)
+		}
+	}
+
+	// 随 family 一起吊销的最新 token 只是失效，不会再次触发泄露处理
+	if _, err := RotateRefreshToken(second.RefreshToken, "", "device-1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("revoked latest token error = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if _, err := RotateRefreshToken(other.RefreshToken, "", "device-2"); err != nil {
+		t.Fatalf("other session was revoked: %v", err)
+	}
+}
+
+// 并发刷新时没抢到轮换的请求只是失败，不能吊销 family，胜出的一方拿到的 token 仍然可用
+func TestRotateRefreshTokenLostRaceKeepsFamily(t *testing.T) {
+	setupTokenTest(t)
+
+	first, err := IssueTokenPair(&models.Session{ID: "session-1", UserID: "user-1", DeviceID: "device-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个请求都读到了未吊销的 token，其中一个先完成了轮换
+	var stale models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", hashToken(first.RefreshToken)).First(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	winner, err := RotateRefreshToken(first.RefreshToken, "", "device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotateRefreshToken(stale); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("losing rotation = %v, want ErrInvalidRefreshToken", err)
+	}
+	tokens := familyTokens(t, first.FamilyID)
+	if len(tokens) != 2 || tokens[1].RevokedAt != nil {
+		t.Fatalf("family after lost race = %+v", tokens)
+	}
+	if _, err := RotateRefreshToken(winner.RefreshToken, "", "device-1"); err != nil {
+		t.Fatalf("winner's token revoked: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenRejects(t *testing.T) {
+	setupTokenTest(t)
+
+	tests := []struct {
+		name     string
+		clientID string
+		deviceID string
+		prepare  func(pair *TokenPair)
+		raw      string
+	}{
+		{name: "unknown token", deviceID: "device-1", raw: "not-a-token"},
+		{name: "other device", deviceID: "device-2"},
+		{name: "other client", clientID: "app", deviceID: "device-1"},
+		{
+			name:     "expired",
+			deviceID: "device-1",
+			prepare: func(pair *TokenPair) {
+				database.DB.Model(&models.RefreshToken{}).Where("family_id = ?", pair.FamilyID).
+					Update("expires_at", time.Now().Add(-time.Minute))
+			},
+		},
+		{
+			name:     "revoked on logout",
+			deviceID: "device-1",
+			prepare: func(pair *TokenPair) {
+				if err := RevokeTokenFamily(pair.FamilyID); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := IssueTokenPair(&models.Session{ID: "session-1", UserID: "user-1", DeviceID: "device-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(pair)
+			}
+			raw := pair.RefreshToken
+			if tt.raw != "" {
+				raw = tt.raw
+			}
+
+			if _, err := RotateRefreshToken(raw, tt.clientID, tt.deviceID); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Fatalf("RotateRefreshToken() = %v, want ErrInvalidRefreshToken", err)
+			}
+			// 被拒绝的请求不能消耗 token，也不能把它标记成已轮换
+			for _, token := range familyTokens(t, pair.FamilyID) {
+				if token.ReplacedBy != "" {
+					t.Errorf("rejected rotation replaced token %s", token.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshTokenStoredHashed(t *testing.T) {
+	setupTokenTest(t)
+
+	pair, err := IssueClientTokenPair("user-1", "app", "openid profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := FindRefreshToken(pair.RefreshToken)
+	if stored == nil {
+		t.Fatal("FindRefreshToken() = nil")
+	}
+	if stored.TokenHash == pair.RefreshToken || stored.TokenHash != hashToken(pair.RefreshToken) {
+		t.Errorf("token stored as %q", stored.TokenHash)
+	}
+	if stored.ClientID != "app" || stored.Scope != "openid profile" {
+		t.Errorf("stored token = %+v", stored)
+	}
+
+	rotated, err := RotateRefreshToken(pair.RefreshToken, "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.ClientID != "app" || rotated.Scope != "openid profile" {
+		t.Errorf("rotated pair lost its grant: %+v", rotated)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionInfo struct {
+	DeviceID   string
+	DeviceName string
+	Platform   string
+	IP         string
+	UserAgent  string
+}
+
+// CreateSession 为一次登录创建设备会话，同一设备上旧的会话会被吊销
+func CreateSession(ctx context.Context, userID string, info SessionInfo) (*models.Session, error) {
+	if info.DeviceID == "" {
+		info.DeviceID = uuid.New().String()
+	}
+
+	var previous []models.Session
+	database.DB.Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, info.DeviceID).Find(&previous)
+	for _, session := range previous {
+		if err := revokeSession(ctx, &session); err != nil {
+			log.Printf("Failed to revoke previous session %s: %v", session.ID, err)
+		}
+	}
+
+	session := &models.Session{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		DeviceID:     info.DeviceID,
+		DeviceName:   truncate(info.DeviceName, 100),
+		Platform:     truncate(info.Platform, 20),
+		IP:           info.IP,
+		UserAgent:    truncate(info.UserAgent, 255),
+		LastActiveAt: time.Now(),
+		CreatedAt:    time.Now(),
+	}
+
+	if err := database.DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListSessions 返回用户所有未吊销的会话，最近活跃的在前
+func ListSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_active_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 吊销用户的某个会话：作废其 refresh token、拒绝其 access token，并断开其 WebSocket 连接
+func RevokeSession(ctx context.Context, userID, sessionID string) error {
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	return revokeSession(ctx, &session)
+}
+
+// TouchSession 刷新会话的最近活跃时间和 IP
+func TouchSession(sessionID, ip string) {
+	if sessionID == "" {
+		return
+	}
+
+	updates := map[string]interface{}{"last_active_at": time.Now()}
+	if ip != "" {
+		updates["ip"] = ip
+	}
+	database.DB.Model(&models.Session{}).Where("id = ?", sessionID).Updates(updates)
+}
+
+func revokeSession(ctx context.Context, session *models.Session) error {
+	if err := database.DB.Model(&models.Session{}).Where("id = ?", session.ID).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", session.ID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	// 已签发的 access token 最长存活 AccessTokenTTL，denylist 只需保留这么久
+	if err := revocation.RevokeSession(ctx, session.ID, jwt.AccessTokenTTL()); err != nil {
+		return err
+	}
+
+	if err := revocation.Publish(ctx, revocation.Event{UserID: session.UserID, SessionID: session.ID}); err != nil {
+		log.Printf("Failed to publish session revocation %s: %v", session.ID, err)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// throttlePolicy 窗口内失败次数超过 delayAfter 后，每次失败都要等待翻倍的时间才能重试；
+// 达到 lockoutAfter 时封锁 lockout 时长
+type throttlePolicy struct {
+	delayAfter   int
+	lockoutAfter int
+	lockout      time.Duration
+}
+
+var (
+	loginFailureWindow = 15 * time.Minute
+	maxLoginDelay      = 30 * time.Second
+	// 2^6 秒已经超过 maxLoginDelay
+	maxLoginDelayShift = 6
+
+	// 同一 IP 后面可能是整个公司的出口，阈值比单个用户名宽松得多
+	usernameThrottle = throttlePolicy{delayAfter: 3, lockoutAfter: 10, lockout: 15 * time.Minute}
+	ipThrottle       = throttlePolicy{delayAfter: 20, lockoutAfter: 100, lockout: 15 * time.Minute}
+)
+
+// ClientInfo 发起登录请求的客户端，用于按 IP 限流和审计
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// ThrottledError 登录尝试过于频繁，需要等待 RetryAfter 之后才能再试
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter)
+}
+
+// RetryAfterSeconds 向上取整的等待秒数，用于 Retry-After 响应头
+func (e *ThrottledError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// checkLoginThrottle 在校验密码之前调用，用户名或 IP 被封锁时直接拒绝。
+// username 为空时只检查 IP，用于设备 user_code 这类没有用户名的猜测。
+// 限流存储不可用时放行，避免 Redis 故障导致所有人都无法登录
+func checkLoginThrottle(ctx context.Context, username string, client ClientInfo) error {
+	var wait time.Duration
+	for _, key := range throttleKeys(username, client.IP) {
+		remaining, err := ratelimit.BlockedFor(ctx, key)
+		if err != nil {
+			log.Printf("Failed to check login throttle %s: %v", key, err)
+			continue
+		}
+		wait = max(wait, remaining)
+	}
+
+	if wait > 0 {
+		RecordAuditEvent(models.AuditEvent{
+			Type:      AuditLoginThrottled,
+			Username:  username,
+			IP:        client.IP,
+			UserAgent: client.UserAgent,
+			Detail:    "retry after " + wait.Round(time.Second).String(),
+		})
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次失败的登录（密码、第二因素或设备 user_code 错误），并按失败次数施加延迟或封锁
+func recordLoginFailure(ctx context.Context, username, userID string, client ClientInfo, reason string) {
+	RecordAuditEvent(models.AuditEvent{
+		Type:      AuditLoginFailed,
+		UserID:    userID,
+		Username:  username,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Detail:    reason,
+	})
+
+	policies := make(map[string]throttlePolicy)
+	if username != "" {
+		policies[throttleKey("user", username)] = usernameThrottle
+	}
+	if client.IP != "" {
+		policies[throttleKey("ip", client.IP)] = ipThrottle
+	}
+
+	for key, policy := range policies {
+		failures, err := ratelimit.Hit(ctx, key, loginFailureWindow)
+		if err != nil {
+			log.Printf("Failed to record login failure %s: %v", key, err)
+			continue
+		}
+
+		penalty, locked := policy.penalty(failures)
+		if err := ratelimit.Block(ctx, key, penalty); err != nil {
+			log.Printf("Failed to throttle %s: %v", key, err)
+		}
+
+		if locked {
+			RecordAuditEvent(models.AuditEvent{
+				Type:      AuditAccountLocked,
+				UserID:    userID,
+				Username:  username,
+				IP:        client.IP,
+				UserAgent: client.UserAgent,
+				Detail:    fmt.Sprintf("%s locked for %s after %d failures", key, penalty, failures),
+			})
+		}
+	}
+}
+
+// resetLoginFailures 登录完全成功后清空该用户名的失败计数。IP 计数不清空，
+// 否则攻击者可以用自己的账号穿插登录来绕过 IP 限制
+func resetLoginFailures(ctx context.Context, username string) {
+	if err := ratelimit.Reset(ctx, throttleKey("user", username)); err != nil {
+		log.Printf("Failed to reset login failures for %s: %v", username, err)
+	}
+}
+
+func (p throttlePolicy) penalty(failures int) (time.Duration, bool) {
+	if failures >= p.lockoutAfter {
+		return p.lockout, true
+	}
+	if failures <= p.delayAfter {
+		return 0, false
+	}
+
+	// 先限制指数再移位：IP 的封锁阈值很高，直接移位会溢出成负数或 0，延迟反而消失
+	shift := min(failures-p.delayAfter-1, maxLoginDelayShift)
+	delay := time.Second << shift
+	return min(delay, maxLoginDelay), false
+}
+
+func throttleKeys(username, ip string) []string {
+	var keys []string
+	if username != "" {
+		keys = append(keys, throttleKey("user", username))
+	}
+	if ip != "" {
+		keys = append(keys, throttleKey("ip", ip))
+	}
+	return keys
+}
+
+// 用户名不区分大小写，避免通过改变大小写绕过计数
+func throttleKey(kind, value string) string {
+	return "login:" + kind + ":" + strings.ToLower(value)
+}
+
+func writeThrottled(c *gin.Context, err *ThrottledError) {
+	seconds := err.RetryAfterSeconds()
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("尝试次数过多，请 %d 秒后再试", seconds),
+		"retry_after": seconds,
+	})
+}
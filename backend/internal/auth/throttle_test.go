@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestThrottlePolicyPenalty(t *testing.T) {
+	for name, policy := range map[string]throttlePolicy{
+		"username": usernameThrottle,
+		"ip":       ipThrottle,
+	} {
+		t.Run(name, func(t *testing.T) {
+			prev := time.Duration(0)
+			for failures := 1; failures <= policy.lockoutAfter; failures++ {
+				penalty, locked := policy.penalty(failures)
+
+				switch {
+				case failures >= policy.lockoutAfter:
+					if !locked || penalty != policy.lockout {
+						t.Fatalf("failures=%d: got (%s, %v), want lockout %s", failures, penalty, locked, policy.lockout)
+					}
+				case failures <= policy.delayAfter:
+					if locked || penalty != 0 {
+						t.Fatalf("failures=%d: got (%s, %v), want no delay", failures, penalty, locked)
+					}
+				default:
+					if locked {
+						t.Fatalf("failures=%d: locked before lockoutAfter", failures)
+					}
+					// 延迟必须始终为正且不递减，溢出会让延迟变成负数或 0
+					if penalty <= 0 || penalty > maxLoginDelay || penalty < prev {
+						t.Fatalf("failures=%d: got delay %s after %s", failures, penalty, prev)
+					}
+					prev = penalty
+				}
+			}
+		})
+	}
+}
+
+func TestThrottlePolicyPenaltyDelays(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 20, want: 0},
+		{failures: 21, want: time.Second},
+		{failures: 22, want: 2 * time.Second},
+		{failures: 25, want: 16 * time.Second},
+		{failures: 26, want: maxLoginDelay},
+		{failures: 55, want: maxLoginDelay},
+		{failures: 85, want: maxLoginDelay},
+		{failures: 99, want: maxLoginDelay},
+	}
+	for _, tt := range tests {
+		if got, _ := ipThrottle.penalty(tt.failures); got != tt.want {
+			t.Errorf("ipThrottle.penalty(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// 经网关转发的登录按 X-Forwarded-For 中的客户端地址分别计数，一个客户端被限流不影响其他客户端
+func TestLoginThrottleUsesForwardedClientIP(t *testing.T) {
+	setupTokenTest(t)
+	ratelimit.Init(nil)
+	t.Cleanup(func() { ratelimit.Init(nil) })
+
+	const gatewayIP = "172.28.0.10"
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{gatewayIP}); err != nil {
+		t.Fatal(err)
+	}
+	router.POST("/login", func(c *gin.Context) {
+		client := ClientInfo{IP: c.ClientIP()}
+		if err := checkLoginThrottle(c.Request.Context(), "", client); err != nil {
+			c.Status(http.StatusTooManyRequests)
+			return
+		}
+		recordLoginFailure(c.Request.Context(), "", "", client, "invalid credentials")
+		c.Status(http.StatusUnauthorized)
+	})
+
+	login := func(remoteIP, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteIP + ":12345"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i <= ipThrottle.delayAfter; i++ {
+		if code := login(gatewayIP, "198.51.100.7"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d = %d, want 401", i+1, code)
+		}
+	}
+	if code := login(gatewayIP, "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("throttled client = %d, want 429", code)
+	}
+	if code := login(gatewayIP, "203.0.113.9"); code != http.StatusUnauthorized {
+		t.Fatalf("other client behind the gateway = %d, want 401", code)
+	}
+	// 不受信任的对端带来的 X-Forwarded-For 被忽略，按对端地址计数
+	if code := login("192.0.2.1", "198.51.100.7"); code != http.StatusUnauthorized {
+		t.Fatalf("untrusted peer = %d, want 401", code)
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 发布验签公钥，网关和第三方系统据此校验 token 而无需持有签名密钥
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwt.PublicJWKS())
+}
+
+// OpenIDConfiguration OIDC discovery 文档，issuer 由 OIDC_ISSUER 配置
+func OpenIDConfiguration(c *gin.Context) {
+	issuer := jwt.Issuer()
+
+	scopes := make([]string, 0, len(supportedScopes))
+	for scope := range supportedScopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/api/v1/auth/oauth2/authorize",
+		"token_endpoint":                        issuer + "/api/v1/auth/oauth2/token",
+		"device_authorization_endpoint":         issuer + "/api/v1/auth/oauth2/device_authorization",
+		"userinfo_endpoint":                     issuer + "/api/v1/auth/oauth2/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"scopes_supported":                      scopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": jwt.SigningAlgorithms(),
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{codeChallengeMethodS256},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "email"},
+	})
+}
@@ -73,7 +73,16 @@ func GetFriends(c *gin.Context) {
 	var users []models.User
 	database.DB.Where("id IN ?", friendIDs).Find(&users)
 
-	c.JSON(http.StatusOK, gin.H{"friends": users})
+	// 只返回公开资料，不暴露好友的账号安全设置
+	var result []gin.H
+	for _, user := range users {
+		result = append(result, gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"friends": result})
 }
 
 func SearchUser(c *gin.Context) {
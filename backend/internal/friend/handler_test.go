@@ -0,0 +1,79 @@
+package friend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/database/dbtest"
+	"github.com/gin-gonic/gin"
+)
+
+// 好友列表只包含公开资料，不能看到对方是否开启了两步验证或使用哪种登录方式
+func TestGetFriendsReturnsPublicProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Setup(t)
+
+	users := []models.User{
+		{ID: "user-1", Username: "alice", Email: "alice@example.com", Status: models.UserStatusActive},
+		{ID: "user-2", Username: "bob", Email: "bob@example.com", Status: models.UserStatusActive, AuthProvider: "ldap", TOTPSecret: "secret", TOTPEnabled: true},
+	}
+	for i := range users {
+		if err := database.DB.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.DB.Create(&models.Friend{UserID: "user-1", FriendID: "user-2", Status: "accepted", CreatedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/friends", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		GetFriends(c)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/friends", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Friends []map[string]interface{} `json:"friends"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Friends) != 1 {
+		t.Fatalf("friends = %v", resp.Friends)
+	}
+	want := map[string]interface{}{"id": "user-2", "username": "bob", "email": "bob@example.com"}
+	if len(resp.Friends[0]) != len(want) {
+		t.Fatalf("friend = %v, want only %v", resp.Friends[0], want)
+	}
+	for k, v := range want {
+		if resp.Friends[0][k] != v {
+			t.Errorf("friend[%q] = %v, want %v", k, resp.Friends[0][k], v)
+		}
+	}
+}
+
+func TestUserJSONHidesSecuritySettings(t *testing.T) {
+	data, err := json.Marshal(models.User{ID: "user-1", AuthProvider: "ldap", TOTPSecret: "secret", TOTPEnabled: true, TOTPLastStep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"auth_provider", "totp_secret", "totp_enabled", "totp_last_step"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("user JSON exposes %s: %s", key, data)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	wsPkg "github.com/cyperlo/im/pkg/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+var adminUserIDs = map[string]bool{}
+
+// InitAdmins 设置管理员用户 ID 列表（逗号分隔），为空时管理接口对所有人关闭
+func InitAdmins(userIDs string) {
+	adminUserIDs = map[string]bool{}
+	for _, id := range strings.Split(userIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			adminUserIDs[id] = true
+		}
+	}
+	if len(adminUserIDs) > 0 {
+		log.Printf("Gateway admins: %d users", len(adminUserIDs))
+	}
+}
+
+// RequireAdmin 只允许管理员本人的登录 token 访问，应用 token 和 API key 一律拒绝。需放在 AuthMiddleware 之后
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("principal_type") != "user" || !adminUserIDs[c.GetString("user_id")] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+type NodeConnections struct {
+	NodeID      string   `json:"node_id"`
+	Connections []string `json:"connections"`
+}
+
+type UserConnections struct {
+	UserID string            `json:"user_id"`
+	Nodes  []NodeConnections `json:"nodes"`
+}
+
+// GetConnections 按节点列出用户的 WebSocket 连接。可用 user_id 指定用户（逗号分隔），不指定时列出全部在线用户
+func GetConnections(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var userIDs []string
+	for _, id := range strings.Split(c.Query("user_id"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		users, err := hub.ConnectedUsers(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取在线用户失败"})
+			return
+		}
+		userIDs = users
+	}
+
+	conns, err := hub.Connections(ctx, userIDs...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取连接信息失败"})
+		return
+	}
+
+	result := make([]UserConnections, 0, len(userIDs))
+	for _, userID := range userIDs {
+		nodes := conns[userID]
+		if len(nodes) == 0 {
+			continue
+		}
+		user := UserConnections{UserID: userID, Nodes: make([]NodeConnections, 0, len(nodes))}
+		for nodeID, ids := range nodes {
+			sort.Strings(ids)
+			user.Nodes = append(user.Nodes, NodeConnections{NodeID: nodeID, Connections: ids})
+		}
+		sort.Slice(user.Nodes, func(i, j int) bool { return user.Nodes[i].NodeID < user.Nodes[j].NodeID })
+		result = append(result, user)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": hub.NodeID(),
+		"users":   result,
+	})
+}
+
+// GetSendStats 返回本节点发送队列的丢弃、合并和慢连接断开计数
+func GetSendStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": hub.NodeID(),
+		"stats":   wsPkg.Stats(),
+	})
+}
@@ -1,51 +1,31 @@
 package gateway
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/cyperlo/im/internal/auth"
-	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/internal/message"
+	"github.com/cyperlo/im/pkg/protocol"
 	wsPkg "github.com/cyperlo/im/pkg/websocket"
 	"github.com/gin-gonic/gin"
 )
 
-type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-}
-
 type SendMessageRequest struct {
 	To      string `json:"to" binding:"required"`
 	Content string `json:"content" binding:"required"`
 }
 
-func Login(c *gin.Context) {
-	var req LoginRequest
+func SendMessage(c *gin.Context) {
+	var req SendMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 简化版：直接生成 token
-	token, err := jwt.GenerateToken(req.Username)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"token":    token,
-		"username": req.Username,
-	})
-}
-
-func SendMessage(c *gin.Context) {
-	var req SendMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if appID := c.GetString("app_id"); appID != "" {
+		sendAppMessage(c, appID, &req)
 		return
 	}
 
@@ -56,7 +36,7 @@ func SendMessage(c *gin.Context) {
 	}
 
 	// 保存消息到数据库
-	_, err := saveMessageToDB(userID.(string), req.To, req.Content)
+	savedMsg, err := saveMessageToDB(userID.(string), req.To, req.Content)
 	if err != nil {
 		log.Printf("Failed to save message: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存消息失败"})
@@ -65,25 +45,25 @@ func SendMessage(c *gin.Context) {
 
 	// 构造消息
 	sender := auth.GetUserByID(userID.(string))
-	msg := WSMessage{
-		Type:         "chat",
+	msg := protocol.ChatMessage{
 		To:           req.To,
 		From:         userID.(string),
 		FromUsername: sender.Username,
 		Content:      req.Content,
 		Timestamp:    time.Now().Unix(),
 	}
+	if savedMsg != nil {
+		msg.MessageID = savedMsg.ID
+		msg.ConversationID = savedMsg.ConversationID
+		msg.Seq = savedMsg.Seq
+	}
 
-	// 通过 WebSocket 广播
-	data, _ := json.Marshal(msg)
-
-	// 发送给接收者
-	toUser := auth.GetUserByUsername(req.To)
-	if toUser != nil {
-		wsPkg.SendToUser(toUser.ID, data)
+	// 通过 WebSocket 推送给接收者和发送者（回显）
+	recipients := []string{userID.(string)}
+	if toUser := auth.GetUserByUsername(req.To); toUser != nil && toUser.ID != userID.(string) {
+		recipients = append(recipients, toUser.ID)
 	}
-	// 发送给发送者（回显）
-	wsPkg.SendToUser(userID.(string), data)
+	wsPkg.SendToUsers(recipients, protocol.NewEnvelope(protocol.FrameChat, msg))
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "sent",
@@ -91,7 +71,64 @@ func SendMessage(c *gin.Context) {
 	})
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+// sendAppMessage 应用以自身身份给用户推送消息，to 可以是用户名或用户 ID
+func sendAppMessage(c *gin.Context, appID string, req *SendMessageRequest) {
+	app := auth.GetClientByID(appID)
+	if app == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "应用不存在"})
+		return
+	}
+
+	toUser := auth.GetUserByUsername(req.To)
+	if toUser == nil {
+		toUser = auth.GetUserByID(req.To)
+	}
+	if toUser == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	conversation, err := message.GetOrCreateAppConversation(app.ID, app.Name, toUser.ID)
+	if err != nil {
+		log.Printf("Failed to create app conversation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败"})
+		return
+	}
+
+	savedMsg, err := message.SaveMessage(conversation.ID, app.ID, "app", req.Content)
+	if err != nil {
+		log.Printf("Failed to save app message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存消息失败"})
+		return
+	}
+
+	msg := protocol.ChatMessage{
+		To:           toUser.Username,
+		From:         app.ID,
+		FromUsername: app.Name,
+		SenderType:   "app",
+		Content:      req.Content,
+		Timestamp:    savedMsg.CreatedAt.Unix(),
+		MessageID:    savedMsg.ID,
+
+		ConversationID: conversation.ID,
+		Seq:            savedMsg.Seq,
+	}
+
+	wsPkg.SendToUser(toUser.ID, protocol.NewEnvelope(protocol.FrameChat, msg))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "sent",
+		"message_id":      savedMsg.ID,
+		"conversation_id": conversation.ID,
+		"seq":             savedMsg.Seq,
+		"timestamp":       msg.Timestamp,
+	})
+}
+
+// AuthMiddleware 校验 Bearer token。IM 自身签发给用户的 token 可访问所有接口；
+// 应用 token 和第三方代用户申请的 token 只能访问声明了 scopes 的接口，且必须持有全部 scope。
+func AuthMiddleware(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -104,14 +141,48 @@ func AuthMiddleware() gin.HandlerFunc {
 			token = token[7:]
 		}
 
-		claims, err := jwt.ValidateToken(token)
+		// API key 代表创建它的用户，但和第三方应用一样只能访问声明了 scope 的接口
+		if auth.IsAPIKey(token) {
+			key, err := auth.ValidateAPIKey(c.Request.Context(), token, c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 API key"})
+				c.Abort()
+				return
+			}
+			if len(scopes) == 0 || !auth.APIKeyHasScopes(key, scopes...) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key 权限不足"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", key.UserID)
+			c.Set("api_key_id", key.ID)
+			c.Set("principal_type", "api_key")
+			c.Next()
+			return
+		}
+
+		claims, err := auth.ValidateAccessToken(c.Request.Context(), token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
+		if claims.ClientID != "" && (len(scopes) == 0 || !claims.HasScopes(scopes...)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "token 权限不足"})
+			c.Abort()
+			return
+		}
+
+		if claims.IsApp() {
+			c.Set("app_id", claims.ClientID)
+			c.Set("principal_type", "app")
+		} else {
+			c.Set("user_id", claims.UserID)
+			c.Set("session_id", claims.SessionID)
+			c.Set("principal_type", "user")
+		}
 		c.Next()
 	}
 }
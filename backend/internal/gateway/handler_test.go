@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewarePrincipals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := jwt.Init(jwt.Config{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(claims jwt.Claims) string {
+		signed, err := jwt.GenerateAccessToken(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	user := token(jwt.Claims{UserID: "user-1", SessionID: "session-1"})
+	delegated := token(jwt.Claims{UserID: "user-1", ClientID: "app-1", Scope: "openid messages:send"})
+	app := token(jwt.Claims{ClientID: "app-1", Scope: "messages:send"})
+
+	router := gin.New()
+	principal := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"type":    c.GetString("principal_type"),
+			"user_id": c.GetString("user_id"),
+			"app_id":  c.GetString("app_id"),
+		})
+	}
+	router.GET("/unscoped", AuthMiddleware(), principal)
+	router.GET("/send", AuthMiddleware("messages:send"), principal)
+	router.GET("/read", AuthMiddleware("messages:send", "conversations:read"), principal)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		status     int
+		wantType   string
+		wantUserID string
+		wantAppID  string
+	}{
+		{name: "missing token", path: "/send", status: http.StatusUnauthorized},
+		{name: "invalid token", path: "/send", token: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "user token on unscoped route", path: "/unscoped", token: user, status: http.StatusOK, wantType: "user", wantUserID: "user-1"},
+		{name: "user token ignores scopes", path: "/read", token: user, status: http.StatusOK, wantType: "user", wantUserID: "user-1"},
+		{name: "app token", path: "/send", token: app, status: http.StatusOK, wantType: "app", wantAppID: "app-1"},
+		{name: "app token on unscoped route", path: "/unscoped", token: app, status: http.StatusForbidden},
+		{name: "app token missing a scope", path: "/read", token: app, status: http.StatusForbidden},
+		{name: "delegated token acts as the user", path: "/send", token: delegated, status: http.StatusOK, wantType: "user", wantUserID: "user-1"},
+		{name: "delegated token on unscoped route", path: "/unscoped", token: delegated, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				Type   string `json:"type"`
+				UserID string `json:"user_id"`
+				AppID  string `json:"app_id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.wantType || got.UserID != tt.wantUserID || got.AppID != tt.wantAppID {
+				t.Errorf("principal = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 登录请求体很小，超过此大小直接拒绝
+const maxLoginBodySize = 64 << 10
+
+var (
+	authServiceURL = "http://localhost:8081"
+	authClient     = &http.Client{Timeout: 10 * time.Second}
+)
+
+// InitAuthService 设置认证服务地址。网关不持有签名私钥，登录统一交给认证服务完成，
+// 密码校验、两步验证和登录限流只在一处实现
+func InitAuthService(baseURL string) {
+	if baseURL != "" {
+		authServiceURL = strings.TrimSuffix(baseURL, "/")
+	}
+	log.Printf("Gateway login forwarding to auth service at %s", authServiceURL)
+}
+
+// Login 转发到认证服务的 /api/v1/auth/login，响应与其完全一致
+func Login(c *gin.Context) {
+	forwardToAuth(c, "/api/v1/auth/login")
+}
+
+// LoginMFA 转发两步登录的第二步
+func LoginMFA(c *gin.Context) {
+	forwardToAuth(c, "/api/v1/auth/login/2fa")
+}
+
+func forwardToAuth(c *gin.Context, path string) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoginBodySize+1))
+	if err != nil || len(body) > maxLoginBodySize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求体无效"})
+		return
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, authServiceURL+path, bytes.NewReader(body))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return
+	}
+	req.Header.Set("Content-Type", c.ContentType())
+	req.Header.Set("User-Agent", c.Request.UserAgent())
+	// 认证服务按客户端 IP 限流和审计，因此传递网关看到的真实 IP 而不是网关自身的地址。
+	// 客户端带来的 X-Forwarded-For 整体丢弃，ClientIP 只在对端是 TRUSTED_PROXIES 中的代理时才采用它
+	clientIP := c.ClientIP()
+	req.Header.Set("X-Forwarded-For", clientIP)
+	req.Header.Set("X-Real-IP", clientIP)
+
+	resp, err := authClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to reach auth service: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "认证服务不可用"})
+		return
+	}
+	defer resp.Body.Close()
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		c.Header("Retry-After", retryAfter)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(resp.StatusCode, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, nil)
+}
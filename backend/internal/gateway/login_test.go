@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForwardToAuthClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var forwarded, realIP string
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get("X-Forwarded-For")
+		realIP = r.Header.Get("X-Real-IP")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer authServer.Close()
+
+	oldURL := authServiceURL
+	authServiceURL = authServer.URL
+	defer func() { authServiceURL = oldURL }()
+
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{name: "spoofed header from client", proxies: nil, remote: "203.0.113.7:40000", want: "203.0.113.7"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remote: "203.0.113.7:40000", want: "203.0.113.7"},
+		{name: "trusted load balancer", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:40000", want: "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatal(err)
+			}
+			r.POST("/login", Login)
+
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(`{}`))
+			req.RemoteAddr = tt.remote
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.9")
+			req.Header.Set("X-Real-IP", "1.2.3.4")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			if forwarded != tt.want || realIP != tt.want {
+				t.Errorf("forwarded X-Forwarded-For=%q X-Real-IP=%q, want %q", forwarded, realIP, tt.want)
+			}
+		})
+	}
+}
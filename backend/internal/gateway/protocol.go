@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/protocol"
+	wsPkg "github.com/cyperlo/im/pkg/websocket"
+)
+
+// ProtocolError 处理帧失败的原因，会以 error 帧返回给客户端
+type ProtocolError struct {
+	Code   string
+	Reason string
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Code + ": " + e.Reason
+}
+
+func newProtocolError(code, reason string) *ProtocolError {
+	return &ProtocolError{Code: code, Reason: reason}
+}
+
+// decodePayload 解析信封中的 payload，缺失或格式错误时返回 bad_request
+func decodePayload(env *protocol.Envelope, v interface{}) *ProtocolError {
+	if len(env.Payload) == 0 {
+		return newProtocolError(protocol.ErrCodeBadRequest, "missing payload")
+	}
+	if err := json.Unmarshal(env.Payload, v); err != nil {
+		return newProtocolError(protocol.ErrCodeBadRequest, "invalid payload: "+err.Error())
+	}
+	return nil
+}
+
+// reply 只回复给发起请求的连接，按该连接协商的编解码器编码
+func reply(client *wsPkg.Client, frameType, reqID string, payload interface{}) {
+	env := protocol.NewEnvelope(frameType, payload)
+	env.ReqID = reqID
+	if !hub.SendToClient(client, env) {
+		log.Printf("Failed to reply %s to conn %s", frameType, client.ID)
+	}
+}
+
+func replyAck(client *wsPkg.Client, reqID string, msg *models.Message) {
+	reply(client, protocol.FrameAck, reqID, protocol.AckReply{MessageID: msg.ID, ConversationID: msg.ConversationID, Seq: msg.Seq})
+}
+
+func replyError(client *wsPkg.Client, reqID string, err *ProtocolError) {
+	log.Printf("WebSocket request %s from conn %s failed: %v", reqID, client.ID, err)
+	reply(client, protocol.FrameError, reqID, protocol.ErrorReply{Code: err.Code, Reason: err.Reason})
+}
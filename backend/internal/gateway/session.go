@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cyperlo/im/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionResult struct {
+	ID           string    `json:"id"`
+	DeviceID     string    `json:"device_id"`
+	DeviceName   string    `json:"device_name"`
+	Platform     string    `json:"platform"`
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	Current      bool      `json:"current"`
+	Online       bool      `json:"online"`
+}
+
+// GetSessions 列出当前用户登录的所有设备，以及每个设备在任意网关节点上是否有 WebSocket 连接
+func GetSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	currentSessionID := c.GetString("session_id")
+
+	sessions, err := auth.ListSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话列表失败"})
+		return
+	}
+
+	online := hub.OnlineSessions(c.Request.Context(), userID)
+	result := make([]SessionResult, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, SessionResult{
+			ID:           s.ID,
+			DeviceID:     s.DeviceID,
+			DeviceName:   s.DeviceName,
+			Platform:     s.Platform,
+			IP:           s.IP,
+			UserAgent:    s.UserAgent,
+			LastActiveAt: s.LastActiveAt,
+			CreatedAt:    s.CreatedAt,
+			Current:      s.ID == currentSessionID,
+			Online:       online[s.ID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+// RevokeSession 远程注销某个设备
+func RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessionID := c.Param("id")
+
+	if err := auth.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+			return
+		}
+		log.Printf("Failed to revoke session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注销会话失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已注销该设备"})
+}
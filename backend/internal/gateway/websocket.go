@@ -1,42 +1,54 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/cyperlo/im/internal/auth"
+	"github.com/cyperlo/im/internal/group"
 	"github.com/cyperlo/im/internal/message"
 	"github.com/cyperlo/im/internal/models"
 	"github.com/cyperlo/im/pkg/database"
-	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/protocol"
+	"github.com/cyperlo/im/pkg/revocation"
 	wsPkg "github.com/cyperlo/im/pkg/websocket"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// 客户端通过 Sec-WebSocket-Protocol 选择编解码器，未携带时使用旧格式
 var upgrader = websocket.Upgrader{
+	Subprotocols: protocol.Subprotocols,
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
+// WSMessage 不带版本号的旧格式帧
 type WSMessage struct {
 	Type         string `json:"type"`
 	To           string `json:"to,omitempty"`
 	From         string `json:"from,omitempty"`
 	FromUsername string `json:"from_username,omitempty"`
+	SenderType   string `json:"sender_type,omitempty"`
 	Content      string `json:"content,omitempty"`
 	Timestamp    int64  `json:"timestamp,omitempty"`
 	MessageID    string `json:"message_id,omitempty"`
-}
+	DeliveryID   string `json:"delivery_id,omitempty"`
 
-var hub = &wsPkg.Hub{
-	Clients: make(map[string]*wsPkg.Client),
+	// 推送的消息带上所在会话和会话内序号，客户端据此维护同步游标
+	ConversationID string `json:"conversation_id,omitempty"`
+	Seq            int64  `json:"seq,omitempty"`
 }
 
+var hub = wsPkg.NewHub()
+
 func init() {
 	wsPkg.GlobalHub = hub
 }
@@ -48,8 +60,9 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	claims, err := jwt.ValidateToken(token)
-	if err != nil {
+	// 第三方应用的 token 不能建立 WebSocket 连接
+	claims, err := auth.ValidateAccessToken(c.Request.Context(), token)
+	if err != nil || claims.UserID == "" || claims.ClientID != "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 token"})
 		return
 	}
@@ -60,124 +73,341 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	client := &wsPkg.Client{
-		ID:     claims.UserID,
-		UserID: claims.UserID,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
-	}
+	client := wsPkg.NewClient(uuid.New().String(), claims.UserID, claims.SessionID, conn, protocol.CodecFor(conn.Subprotocol()))
+
+	auth.TouchSession(claims.SessionID, c.ClientIP())
 
-	hub.RegisterClient(claims.UserID, client)
+	hub.RegisterClient(client)
 
-	go client.WritePump()
-	go client.ReadPump(hub, claims.UserID, func(message []byte) {
-		handleWebSocketMessage(message, claims.UserID)
+	go client.WritePump(hub)
+	go client.ReadPump(hub, func(message []byte) {
+		handleWebSocketMessage(client, message)
 	})
 }
 
-func handleWebSocketMessage(message []byte, userID string) {
+// 定期检查本节点上的连接所属会话是否已被吊销的周期，须小于 denylist 的保留时间（access token 有效期）
+const revocationSweepInterval = 30 * time.Second
+
+// HandleRevocation 收到吊销事件后断开本节点上对应会话的连接。每个网关节点都订阅吊销事件，
+// 其他节点上的连接由所在节点各自断开；没有会话信息的旧 token 被吊销时断开该用户的全部连接
+func HandleRevocation(event revocation.Event) {
+	handleRevocation(hub, event)
+}
+
+func handleRevocation(h *wsPkg.Hub, event revocation.Event) {
+	if event.SessionID != "" {
+		h.DisconnectSession(event.SessionID, wsPkg.CloseTokenRevoked, "session revoked")
+		return
+	}
+	if event.UserID != "" {
+		h.DisconnectUser(event.UserID, wsPkg.CloseTokenRevoked, "token revoked")
+	}
+}
+
+// SweepRevokedSessions 定期断开会话已被吊销的连接，直到 ctx 结束。吊销事件不保证送达，
+// 节点与 Redis 断开期间错过的事件由此补上
+func SweepRevokedSessions(ctx context.Context) {
+	ticker := time.NewTicker(revocationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepRevokedSessions(ctx, hub)
+		}
+	}
+}
+
+func sweepRevokedSessions(ctx context.Context, h *wsPkg.Hub) {
+	for _, sessionID := range h.SessionIDs() {
+		revoked, err := revocation.IsSessionRevoked(ctx, sessionID)
+		if err != nil {
+			log.Printf("Failed to check revocation of session %s: %v", sessionID, err)
+			return
+		}
+		if revoked {
+			h.DisconnectSession(sessionID, wsPkg.CloseTokenRevoked, "session revoked")
+		}
+	}
+}
+
+// envelopeHandlers 按 type 分发带版本的帧，不在表中的类型一律以 unknown_type 拒绝
+var envelopeHandlers = map[string]func(client *wsPkg.Client, env *protocol.Envelope){
+	protocol.FramePing: func(client *wsPkg.Client, env *protocol.Envelope) {
+		reply(client, protocol.FramePong, env.ReqID, nil)
+	},
+	protocol.FramePong: func(client *wsPkg.Client, env *protocol.Envelope) {},
+	protocol.FrameAck: func(client *wsPkg.Client, env *protocol.Envelope) {
+		var payload protocol.AckPayload
+		if err := decodePayload(env, &payload); err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		hub.Ack(client, payload.DeliveryID)
+	},
+	protocol.FrameChat: func(client *wsPkg.Client, env *protocol.Envelope) {
+		var payload protocol.ChatPayload
+		if err := decodePayload(env, &payload); err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		msg, err := sendChat(client.UserID, payload.To, payload.Content)
+		if err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		replyAck(client, env.ReqID, msg)
+	},
+	protocol.FrameGroupMessage: func(client *wsPkg.Client, env *protocol.Envelope) {
+		var payload protocol.GroupMessagePayload
+		if err := decodePayload(env, &payload); err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		msg, err := sendGroupChat(client.UserID, payload.To, payload.Content)
+		if err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		replyAck(client, env.ReqID, msg)
+	},
+	protocol.FrameTyping: func(client *wsPkg.Client, env *protocol.Envelope) {
+		var payload protocol.TypingPayload
+		if err := decodePayload(env, &payload); err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		if err := sendTyping(client.UserID, payload.ConversationID); err != nil {
+			replyError(client, env.ReqID, err)
+		}
+	},
+	protocol.FrameSync: func(client *wsPkg.Client, env *protocol.Envelope) {
+		var payload protocol.SyncRequest
+		if err := decodePayload(env, &payload); err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		result, err := syncConversation(client.UserID, &payload)
+		if err != nil {
+			replyError(client, env.ReqID, err)
+			return
+		}
+		reply(client, protocol.FrameSync, env.ReqID, result)
+	},
+}
+
+func handleWebSocketMessage(client *wsPkg.Client, raw []byte) {
+	codec := client.Codec
+	if codec == nil {
+		codec = protocol.Legacy
+	}
+
+	env, err := codec.Decode(raw)
+	if err != nil {
+		log.Printf("Invalid message from conn %s: %v", client.ID, err)
+		if codec != protocol.Legacy {
+			replyError(client, "", newProtocolError(protocol.ErrCodeBadRequest, "invalid frame"))
+		}
+		return
+	}
+
+	// 旧客户端不协商子协议，也不带版本号
+	if env.V == 0 && codec == protocol.Legacy {
+		handleLegacyMessage(client, raw)
+		return
+	}
+	if env.V != protocol.Version {
+		replyError(client, env.ReqID, newProtocolError(protocol.ErrCodeUnsupportedVersion, "unsupported protocol version"))
+		return
+	}
+
+	handler, ok := envelopeHandlers[env.Type]
+	if !ok {
+		replyError(client, env.ReqID, newProtocolError(protocol.ErrCodeUnknownType, "unknown frame type: "+env.Type))
+		return
+	}
+	handler(client, env)
+}
+
+// handleLegacyMessage 处理不带版本号的旧格式帧。旧客户端不认识 ack/error 帧，失败只记录日志
+func handleLegacyMessage(client *wsPkg.Client, raw []byte) {
+	userID := client.UserID
+
 	var msg WSMessage
-	if err := json.Unmarshal(message, &msg); err != nil {
+	if err := json.Unmarshal(raw, &msg); err != nil {
 		log.Printf("Invalid message: %v", err)
 		return
 	}
 
+	// 应用层心跳：浏览器无法处理协议层 ping，由客户端定时发送 ping，只回复给这个连接
+	switch msg.Type {
+	case protocol.FramePing:
+		data, _ := json.Marshal(WSMessage{Type: protocol.FramePong, Timestamp: getCurrentTimestamp()})
+		hub.SendRawToClient(client, data)
+		return
+	case protocol.FramePong:
+		return
+	case protocol.FrameAck:
+		// 客户端确认收到推送，停止重投
+		hub.Ack(client, msg.DeliveryID)
+		return
+	case protocol.FrameSync:
+		handleLegacySync(client, raw)
+		return
+	case "recall":
+		log.Printf("Recall message via HTTP API")
+		return
+	}
+
 	log.Printf("Received WebSocket message: type=%s, to=%s, from=%s", msg.Type, msg.To, userID)
 
-	msg.From = userID
-	msg.Timestamp = getCurrentTimestamp()
+	var err *ProtocolError
+	if msg.Type == protocol.FrameGroupMessage {
+		_, err = sendGroupChat(userID, msg.To, msg.Content)
+	} else {
+		_, err = sendChat(userID, msg.To, msg.Content)
+	}
+	if err != nil {
+		log.Printf("Failed to handle %s from user %s: %v", msg.Type, userID, err)
+	}
+}
 
-	// 获取发送者的 username
-	sender := auth.GetUserByID(userID)
-	if sender != nil {
-		msg.FromUsername = sender.Username
+// sendChat 保存单聊消息并推送给接收者和发送者的所有设备
+func sendChat(senderID, to, content string) (*models.Message, *ProtocolError) {
+	if to == "" || content == "" {
+		return nil, newProtocolError(protocol.ErrCodeBadRequest, "to and content are required")
 	}
 
-	// 处理消息撤回
-	if msg.Type == "recall" {
-		log.Printf("Recall message via HTTP API")
-		return
+	toUser := auth.GetUserByUsername(to)
+	if toUser == nil {
+		return nil, newProtocolError(protocol.ErrCodeNotFound, "recipient not found")
 	}
 
-	// 处理群组消息
-	if msg.Type == "group_message" {
-		log.Printf("Processing group message")
-		handleGroupMessage(userID, msg.To, msg.Content)
-		return
+	conversation, err := message.GetOrCreateConversation(senderID, toUser.ID)
+	if err != nil {
+		log.Printf("Failed to get conversation: %v", err)
+		return nil, newProtocolError(protocol.ErrCodeInternal, "failed to create conversation")
 	}
 
-	// 保存消息到数据库
-	savedMsg, err := saveMessageToDB(userID, msg.To, msg.Content)
+	savedMsg, err := message.SaveMessage(conversation.ID, senderID, "user", content)
 	if err != nil {
 		log.Printf("Failed to save message: %v", err)
-	} else {
-		msg.MessageID = savedMsg.ID
+		return nil, newProtocolError(protocol.ErrCodeInternal, "failed to save message")
 	}
 
-	data, _ := json.Marshal(msg)
+	push := protocol.ChatMessage{
+		To:             to,
+		From:           senderID,
+		Content:        content,
+		Timestamp:      savedMsg.CreatedAt.Unix(),
+		MessageID:      savedMsg.ID,
+		ConversationID: savedMsg.ConversationID,
+		Seq:            savedMsg.Seq,
+	}
+	if sender := auth.GetUserByID(senderID); sender != nil {
+		push.FromUsername = sender.Username
+	}
 
-	// 根据username查找userID
-	toUser := auth.GetUserByUsername(msg.To)
-	if toUser != nil {
-		wsPkg.SendToUser(toUser.ID, data)
+	recipients := []string{toUser.ID}
+	if senderID != toUser.ID {
+		recipients = append(recipients, senderID)
 	}
-	wsPkg.SendToUser(userID, data)
+	wsPkg.SendToUsers(recipients, protocol.NewEnvelope(protocol.FrameChat, push))
+	return savedMsg, nil
 }
 
-func handleGroupMessage(senderID, groupName, content string) {
-	log.Printf("handleGroupMessage called: senderID=%s, groupName=%s, content=%s", senderID, groupName, content)
+// sendGroupChat 保存群消息并推送给所有成员，只有群成员可以发送
+func sendGroupChat(senderID, groupName, content string) (*models.Message, *ProtocolError) {
+	if groupName == "" || content == "" {
+		return nil, newProtocolError(protocol.ErrCodeBadRequest, "to and content are required")
+	}
 
 	// 根据群组名称查找群组
 	var conversation models.Conversation
 	if err := database.DB.Where("name = ? AND type = ?", groupName, "group").First(&conversation).Error; err != nil {
 		log.Printf("Group not found: %s, error: %v", groupName, err)
-		return
+		return nil, newProtocolError(protocol.ErrCodeNotFound, "group not found")
 	}
-
-	log.Printf("Found group: id=%s, name=%s", conversation.ID, conversation.Name)
-
-	// 保存消息
-	msg := models.Message{
-		ID:             uuid.New().String(),
-		ConversationID: conversation.ID,
-		SenderID:       senderID,
-		SenderType:     "user",
-		ContentType:    "text",
-		Content:        content,
-		Status:         "sent",
+	if !message.IsConversationMember(conversation.ID, senderID) {
+		return nil, newProtocolError(protocol.ErrCodeForbidden, "not a group member")
 	}
 
-	if err := database.DB.Create(&msg).Error; err != nil {
+	msg, err := message.SaveMessage(conversation.ID, senderID, "user", content)
+	if err != nil {
 		log.Printf("Failed to save group message: %v", err)
-		return
+		return nil, newProtocolError(protocol.ErrCodeInternal, "failed to save message")
 	}
 
-	log.Printf("Message saved: id=%s", msg.ID)
+	log.Printf("Group message saved: id=%s, seq=%d", msg.ID, msg.Seq)
 
 	// 广播给群组所有成员
-	var members []models.ConversationMember
-	database.DB.Where("conversation_id = ?", conversation.ID).Find(&members)
+	group.BroadcastToGroup(msg)
+	return msg, nil
+}
+
+// sendTyping 把输入状态推送给会话中的其他成员。输入状态是临时事件，同一用户在同一会话中的事件可以合并
+func sendTyping(userID, conversationID string) *ProtocolError {
+	if conversationID == "" {
+		return newProtocolError(protocol.ErrCodeBadRequest, "conversation_id is required")
+	}
+
+	memberIDs, err := message.ConversationMemberIDs(conversationID)
+	if err != nil {
+		log.Printf("Failed to get members of conversation %s: %v", conversationID, err)
+		return newProtocolError(protocol.ErrCodeInternal, "failed to get members")
+	}
+	if !slices.Contains(memberIDs, userID) {
+		return newProtocolError(protocol.ErrCodeForbidden, "not a conversation member")
+	}
 
-	log.Printf("Found %d members in group", len(members))
+	others := slices.DeleteFunc(memberIDs, func(id string) bool { return id == userID })
+	typing := protocol.Typing{ConversationID: conversationID, UserID: userID}
+	hub.SendEphemeral(others, protocol.NewEnvelope(protocol.FrameTyping, typing), "typing:"+conversationID+":"+userID)
+	return nil
+}
 
-	var sender models.User
-	database.DB.Where("id = ?", senderID).First(&sender)
+func syncConversation(userID string, req *protocol.SyncRequest) (*message.SyncResult, *ProtocolError) {
+	if req.ConversationID == "" || req.AfterSeq < 0 {
+		return nil, newProtocolError(protocol.ErrCodeBadRequest, "invalid sync request")
+	}
 
-	wsMsg := map[string]interface{}{
-		"type":            "group_message",
-		"conversation_id": conversation.ID,
-		"group_name":      conversation.Name,
-		"from":            senderID,
-		"from_username":   sender.Username,
-		"content":         content,
-		"timestamp":       time.Now().Unix(),
+	result, err := message.SyncMessages(req.ConversationID, userID, req.AfterSeq, req.Limit)
+	if err != nil {
+		if errors.Is(err, message.ErrNotMember) {
+			return nil, newProtocolError(protocol.ErrCodeForbidden, "not a conversation member")
+		}
+		log.Printf("Failed to sync conversation %s: %v", req.ConversationID, err)
+		return nil, newProtocolError(protocol.ErrCodeInternal, "sync failed")
 	}
+	return result, nil
+}
 
-	msgBytes, _ := json.Marshal(wsMsg)
+// handleLegacySync 旧格式的同步请求，结果以 sync / sync_error 帧返回
+func handleLegacySync(client *wsPkg.Client, raw []byte) {
+	var req protocol.SyncRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		replyError(client, "", newProtocolError(protocol.ErrCodeBadRequest, "invalid payload: "+err.Error()))
+		return
+	}
 
-	for _, member := range members {
-		log.Printf("Sending message to member: %s", member.UserID)
-		wsPkg.SendToUser(member.UserID, msgBytes)
+	result, err := syncConversation(client.UserID, &req)
+	if err != nil {
+		data, _ := json.Marshal(gin.H{"type": "sync_error", "conversation_id": req.ConversationID, "error": err.Reason})
+		hub.SendRawToClient(client, data)
+		return
+	}
+
+	data, _ := json.Marshal(gin.H{
+		"type":            protocol.FrameSync,
+		"conversation_id": result.ConversationID,
+		"messages":        result.Messages,
+		"last_seq":        result.LastSeq,
+		"has_more":        result.HasMore,
+	})
+	if !hub.SendRawToClient(client, data) {
+		log.Printf("Failed to send sync result of conversation %s to conn %s", req.ConversationID, client.ID)
 	}
 }
 
@@ -198,5 +428,5 @@ func saveMessageToDB(fromUserID, toUsername, content string) (*models.Message, e
 		return nil, err
 	}
 
-	return message.SaveMessage(conversation.ID, fromUserID, content)
+	return message.SaveMessage(conversation.ID, fromUserID, "user", content)
 }
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+	"github.com/cyperlo/im/pkg/revocation"
+	wsPkg "github.com/cyperlo/im/pkg/websocket"
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient 建立一对真实的 WebSocket 连接，把服务端一侧注册到 h，返回客户端一侧
+func newTestClient(t *testing.T, h *wsPkg.Hub, id, userID, sessionID string) *websocket.Conn {
+	_, peer := newTestConn(t, h, id, userID, sessionID)
+	return peer
+}
+
+// newTestConn 与 newTestClient 相同，同时返回注册到 h 的连接
+func newTestConn(t *testing.T, h *wsPkg.Hub, id, userID, sessionID string) (*wsPkg.Client, *websocket.Conn) {
+	t.Helper()
+	serverConn := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serverConn <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	client := wsPkg.NewClient(id, userID, sessionID, <-serverConn, nil)
+	h.RegisterClient(client)
+	t.Cleanup(func() { h.UnregisterClient(client) })
+	return client, peer
+}
+
+// readClose 返回对端收到的关闭码，超时未关闭时返回 0
+func readClose(peer *websocket.Conn, wait time.Duration) int {
+	peer.SetReadDeadline(time.Now().Add(wait))
+	_, _, err := peer.ReadMessage()
+	if closeErr, ok := err.(*websocket.CloseError); ok {
+		return closeErr.Code
+	}
+	return 0
+}
+
+func quietLog(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(out) })
+}
+
+// 吊销事件广播给所有网关节点，每个节点断开自己持有的该会话连接
+func TestRevocationEventDisconnectsSessionOnEveryNode(t *testing.T) {
+	quietLog(t)
+	revocation.Init(nil)
+	t.Cleanup(func() { revocation.Init(nil) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	nodes := []*wsPkg.Hub{wsPkg.NewHub(), wsPkg.NewHub()}
+	for _, node := range nodes {
+		revocation.Subscribe(ctx, func(event revocation.Event) { handleRevocation(node, event) })
+	}
+	phone := newTestClient(t, nodes[0], "phone", "alice", "s1")
+	laptop := newTestClient(t, nodes[1], "laptop", "alice", "s1")
+	other := newTestClient(t, nodes[1], "tablet", "alice", "s2")
+
+	if err := revocation.Publish(ctx, revocation.Event{UserID: "alice", SessionID: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, peer := range map[string]*websocket.Conn{"phone": phone, "laptop": laptop} {
+		if code := readClose(peer, 5*time.Second); code != wsPkg.CloseTokenRevoked {
+			t.Errorf("%s close code = %d, want %d", name, code, wsPkg.CloseTokenRevoked)
+		}
+	}
+	if code := readClose(other, 100*time.Millisecond); code != 0 {
+		t.Errorf("other session closed with %d", code)
+	}
+}
+
+// 错过吊销事件的节点在下一次巡检时断开已吊销会话的连接
+func TestSweepRevokedSessions(t *testing.T) {
+	quietLog(t)
+	revocation.Init(nil)
+	t.Cleanup(func() { revocation.Init(nil) })
+
+	h := wsPkg.NewHub()
+	revoked := newTestClient(t, h, "phone", "alice", "s1")
+	active := newTestClient(t, h, "laptop", "alice", "s2")
+	ctx := context.Background()
+	if err := revocation.RevokeSession(ctx, "s1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	sweepRevokedSessions(ctx, h)
+	if code := readClose(revoked, 5*time.Second); code != wsPkg.CloseTokenRevoked {
+		t.Errorf("revoked session close code = %d, want %d", code, wsPkg.CloseTokenRevoked)
+	}
+	if code := readClose(active, 100*time.Millisecond); code != 0 {
+		t.Errorf("active session closed with %d", code)
+	}
+}
+
+// 旧格式的 sync 请求字段类型错误时回复 bad_request，不能按零值执行同步
+func TestLegacySyncRejectsMalformedRequest(t *testing.T) {
+	quietLog(t)
+
+	client, peer := newTestConn(t, hub, "legacy", "alice", "s1")
+	go client.WritePump(hub)
+
+	handleWebSocketMessage(client, []byte(`{"type":"sync","conversation_id":"c1","after_seq":"abc"}`))
+
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧格式把 payload 的字段平铺在帧上
+	var reply struct {
+		Type string `json:"type"`
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		t.Fatalf("reply %s: %v", data, err)
+	}
+	if reply.Type != protocol.FrameError || reply.Code != protocol.ErrCodeBadRequest {
+		t.Fatalf("reply = %s, want %s error", data, protocol.ErrCodeBadRequest)
+	}
+}
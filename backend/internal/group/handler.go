@@ -1,13 +1,14 @@
 package group
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/cyperlo/im/internal/message"
 	"github.com/cyperlo/im/internal/models"
 	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/protocol"
 	wsPkg "github.com/cyperlo/im/pkg/websocket"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -72,18 +73,11 @@ func CreateGroup(c *gin.Context) {
 }
 
 func notifyGroupCreated(conversationID, groupName string, memberIDs []string) {
-	wsMsg := map[string]interface{}{
-		"type":            "group_created",
-		"conversation_id": conversationID,
-		"group_name":      groupName,
-		"timestamp":       time.Now().Unix(),
-	}
-
-	msgBytes, _ := json.Marshal(wsMsg)
-
-	for _, memberID := range memberIDs {
-		wsPkg.SendToUser(memberID, msgBytes)
-	}
+	wsPkg.SendToUsers(memberIDs, protocol.NewEnvelope(protocol.FrameGroupCreated, protocol.GroupCreated{
+		ConversationID: conversationID,
+		GroupName:      groupName,
+		Timestamp:      time.Now().Unix(),
+	}))
 }
 
 func GetGroups(c *gin.Context) {
@@ -275,64 +269,49 @@ func SendGroupMessage(c *gin.Context) {
 
 	log.Printf("Message content: %s", req.Content)
 
-	msg := models.Message{
-		ID:             uuid.New().String(),
-		ConversationID: conversationID,
-		SenderID:       userID,
-		SenderType:     "user",
-		ContentType:    "text",
-		Content:        req.Content,
-		Status:         "sent",
-	}
-
-	if err := database.DB.Create(&msg).Error; err != nil {
+	msg, err := message.SaveMessage(conversationID, userID, "user", req.Content)
+	if err != nil {
 		log.Printf("Failed to save message: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送失败"})
 		return
 	}
 
-	log.Printf("Message saved: id=%s", msg.ID)
+	log.Printf("Message saved: id=%s, seq=%d", msg.ID, msg.Seq)
 
 	// 广播给群组所有成员
-	BroadcastToGroup(conversationID, userID, req.Content)
+	BroadcastToGroup(msg)
 
 	c.JSON(http.StatusOK, msg)
 }
 
-func BroadcastToGroup(conversationID, senderID, content string) {
-	log.Printf("BroadcastToGroup called: conversationID=%s, senderID=%s", conversationID, senderID)
+// BroadcastToGroup 把已保存的群消息推送给所有成员，推送中带上 seq 供客户端维护同步游标
+func BroadcastToGroup(msg *models.Message) {
+	conversationID, senderID := msg.ConversationID, msg.SenderID
 
-	var members []models.ConversationMember
-	database.DB.Where("conversation_id = ?", conversationID).Find(&members)
-	log.Printf("Found %d members", len(members))
+	memberIDs, err := message.ConversationMemberIDs(conversationID)
+	if err != nil {
+		log.Printf("Failed to get members of group %s: %v", conversationID, err)
+		return
+	}
 
 	var sender models.User
 	database.DB.Where("id = ?", senderID).First(&sender)
-	log.Printf("Sender: id=%s, username=%s", sender.ID, sender.Username)
 
 	var conversation models.Conversation
 	database.DB.Where("id = ?", conversationID).First(&conversation)
-	log.Printf("Conversation: id=%s, name=%s", conversation.ID, conversation.Name)
 
-	wsMsg := map[string]interface{}{
-		"type":            "group_message",
-		"conversation_id": conversationID,
-		"group_name":      conversation.Name,
-		"from":            senderID,
-		"from_username":   sender.Username,
-		"content":         content,
-		"timestamp":       time.Now().Unix(),
-	}
-
-	msgBytes, _ := json.Marshal(wsMsg)
-	log.Printf("Broadcasting message: %s", string(msgBytes))
-
-	for _, member := range members {
-		log.Printf("Sending to member: %s", member.UserID)
-		wsPkg.SendToUser(member.UserID, msgBytes)
+	push := protocol.GroupMessage{
+		ConversationID: conversationID,
+		GroupName:      conversation.Name,
+		From:           senderID,
+		FromUsername:   sender.Username,
+		Content:        msg.Content,
+		MessageID:      msg.ID,
+		Seq:            msg.Seq,
+		Timestamp:      msg.CreatedAt.Unix(),
 	}
 
-	log.Printf("Broadcast complete")
+	wsPkg.SendToUsers(memberIDs, protocol.NewEnvelope(protocol.FrameGroupMessage, push))
 }
 
 func LeaveGroup(c *gin.Context) {
@@ -1,13 +1,14 @@
 package message
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/cyperlo/im/internal/models"
 	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/protocol"
 	wsPkg "github.com/cyperlo/im/pkg/websocket"
 	"github.com/gin-gonic/gin"
 )
@@ -31,7 +32,7 @@ func SendMessage(c *gin.Context) {
 		return
 	}
 
-	message, err := SaveMessage(conversation.ID, userID, req.Content)
+	message, err := SaveMessage(conversation.ID, userID, "user", req.Content)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存消息失败"})
 		return
@@ -59,6 +60,62 @@ func GetConversation(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"messages": messages})
 }
 
+// GetConversationHistory 供用户或持有 conversations:read 的应用读取其所在会话的消息
+func GetConversationHistory(c *gin.Context) {
+	conversationID := c.Param("id")
+	memberID := c.GetString("user_id")
+	if memberID == "" {
+		memberID = c.GetString("app_id")
+	}
+
+	if !IsConversationMember(conversationID, memberID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该会话"})
+		return
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	messages, err := GetConversationMessages(conversationID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// SyncConversation 返回 after_seq 之后的消息，客户端重连后按会话游标补齐离线期间的消息
+func SyncConversation(c *gin.Context) {
+	memberID := c.GetString("user_id")
+	if memberID == "" {
+		memberID = c.GetString("app_id")
+	}
+
+	afterSeq, err := strconv.ParseInt(c.DefaultQuery("after_seq", "0"), 10, 64)
+	if err != nil || afterSeq < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after_seq 无效"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	result, err := SyncMessages(c.Param("id"), memberID, afterSeq, limit)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该会话"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "同步消息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func GetHistory(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -227,29 +284,17 @@ func broadcastRecallMessage(conversationID, messageID, senderID string) {
 		return
 	}
 
-	wsMsg := map[string]interface{}{
-		"type":       "message_recalled",
-		"message_id": messageID,
-		"sender_id":  senderID,
-		"content":    "[消息已撤回]",
-		"timestamp":  time.Now().Unix(),
+	recalled := protocol.MessageRecalled{
+		MessageID: messageID,
+		SenderID:  senderID,
+		Content:   "[消息已撤回]",
+		Timestamp: time.Now().Unix(),
 	}
 
-	msgBytes, _ := json.Marshal(wsMsg)
-
-	if conversation.Type == "group" {
-		// 群组消息：广播给所有成员
-		var members []models.ConversationMember
-		database.DB.Where("conversation_id = ?", conversationID).Find(&members)
-		for _, member := range members {
-			wsPkg.SendToUser(member.UserID, msgBytes)
-		}
-	} else {
-		// 单聊消息：发送给会话双方
-		var members []models.ConversationMember
-		database.DB.Where("conversation_id = ?", conversationID).Find(&members)
-		for _, member := range members {
-			wsPkg.SendToUser(member.UserID, msgBytes)
-		}
+	// 群聊广播给所有成员，单聊发送给会话双方
+	memberIDs, err := ConversationMemberIDs(conversationID)
+	if err != nil {
+		return
 	}
+	wsPkg.SendToUsers(memberIDs, protocol.NewEnvelope(protocol.FrameMessageRecalled, recalled))
 }
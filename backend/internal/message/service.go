@@ -1,32 +1,96 @@
 package message
 
 import (
+	"errors"
 	"time"
 
 	"github.com/cyperlo/im/internal/models"
 	"github.com/cyperlo/im/pkg/database"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-func SaveMessage(conversationID, senderID, content string) (*models.Message, error) {
+const (
+	defaultSyncLimit = 100
+	maxSyncLimit     = 500
+)
+
+var ErrNotMember = errors.New("not a conversation member")
+
+// SyncResult 会话中 seq 大于游标的消息，HasMore 为 true 时客户端应以 LastSeq 为游标继续同步
+type SyncResult struct {
+	ConversationID string           `json:"conversation_id"`
+	Messages       []models.Message `json:"messages"`
+	LastSeq        int64            `json:"last_seq"`
+	HasMore        bool             `json:"has_more"`
+}
+
+// SaveMessage 保存一条文本消息，senderType 为 user、system 或 app。
+// 消息的 seq 在会话内单调递增，通过对会话行加一并在同一事务内读取来分配
+func SaveMessage(conversationID, senderID, senderType, content string) (*models.Message, error) {
 	message := &models.Message{
 		ID:             uuid.New().String(),
 		ConversationID: conversationID,
 		SenderID:       senderID,
-		SenderType:     "user",
+		SenderType:     senderType,
 		ContentType:    "text",
 		Content:        content,
 		Status:         "sent",
 		CreatedAt:      time.Now(),
 	}
 
-	if err := database.DB.Create(message).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Conversation{}).Where("id = ?", conversationID).
+			UpdateColumn("last_seq", gorm.Expr("last_seq + 1")).Error; err != nil {
+			return err
+		}
+
+		var conversation models.Conversation
+		if err := tx.Select("last_seq").Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+			return err
+		}
+		message.Seq = conversation.LastSeq
+
+		return tx.Create(message).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return message, nil
 }
 
+// SyncMessages 返回会话中 seq 大于 afterSeq 的消息，用于断线重连后补齐。memberID 必须是会话成员
+func SyncMessages(conversationID, memberID string, afterSeq int64, limit int) (*SyncResult, error) {
+	if !IsConversationMember(conversationID, memberID) {
+		return nil, ErrNotMember
+	}
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	limit = min(limit, maxSyncLimit)
+
+	// 多取一条用于判断是否还有更多
+	var messages []models.Message
+	if err := database.DB.Where("conversation_id = ? AND seq > ?", conversationID, afterSeq).
+		Order("seq ASC").
+		Limit(limit + 1).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{ConversationID: conversationID, LastSeq: afterSeq}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		result.HasMore = true
+	}
+	if len(messages) > 0 {
+		result.LastSeq = messages[len(messages)-1].Seq
+	}
+	result.Messages = messages
+	return result, nil
+}
+
 func GetConversationMessages(conversationID string, limit int) ([]models.Message, error) {
 	var messages []models.Message
 	err := database.DB.Where("conversation_id = ?", conversationID).
@@ -75,3 +139,63 @@ func GetOrCreateConversation(user1ID, user2ID string) (*models.Conversation, err
 
 	return &conversation, nil
 }
+
+// GetOrCreateAppConversation 获取应用与用户之间的 system 会话，会话名称为应用名称
+func GetOrCreateAppConversation(appID, appName, userID string) (*models.Conversation, error) {
+	var conversation models.Conversation
+
+	err := database.DB.Raw(`
+		SELECT c.* FROM conversations c
+		INNER JOIN conversation_members cm1 ON c.id = cm1.conversation_id
+		INNER JOIN conversation_members cm2 ON c.id = cm2.conversation_id
+		WHERE c.type = 'system'
+		AND cm1.user_id = ?
+		AND cm2.user_id = ?
+		LIMIT 1
+	`, appID, userID).Scan(&conversation).Error
+
+	if err == nil && conversation.ID != "" {
+		return &conversation, nil
+	}
+
+	conversation = models.Conversation{
+		ID:        uuid.New().String(),
+		Type:      "system",
+		Name:      appName,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := database.DB.Create(&conversation).Error; err != nil {
+		return nil, err
+	}
+
+	members := []models.ConversationMember{
+		{ConversationID: conversation.ID, UserID: appID, JoinedAt: time.Now()},
+		{ConversationID: conversation.ID, UserID: userID, JoinedAt: time.Now()},
+	}
+
+	if err := database.DB.Create(&members).Error; err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+// IsConversationMember 判断用户或应用是否属于该会话
+func IsConversationMember(conversationID, memberID string) bool {
+	var count int64
+	database.DB.Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, memberID).
+		Count(&count)
+	return count > 0
+}
+
+// ConversationMemberIDs 返回会话全部成员的 ID
+func ConversationMemberIDs(conversationID string) ([]string, error) {
+	var memberIDs []string
+	err := database.DB.Model(&models.ConversationMember{}).
+		Where("conversation_id = ?", conversationID).
+		Pluck("user_id", &memberIDs).Error
+	return memberIDs, err
+}
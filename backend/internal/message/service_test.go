@@ -0,0 +1,210 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/database/dbtest"
+	"github.com/gin-gonic/gin"
+)
+
+func saveMessages(t *testing.T, conversationID, senderID string, n int) []*models.Message {
+	t.Helper()
+	messages := make([]*models.Message, n)
+	for i := range messages {
+		message, err := SaveMessage(conversationID, senderID, "user", fmt.Sprintf("message %d", i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages[i] = message
+	}
+	return messages
+}
+
+func TestSaveMessageAssignsSeqPerConversation(t *testing.T) {
+	dbtest.Setup(t)
+
+	first, err := GetOrCreateConversation("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GetOrCreateConversation("alice", "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, message := range saveMessages(t, first.ID, "alice", 3) {
+		if message.Seq != int64(i+1) {
+			t.Errorf("message %d seq = %d, want %d", i, message.Seq, i+1)
+		}
+	}
+	if message := saveMessages(t, second.ID, "alice", 1)[0]; message.Seq != 1 {
+		t.Errorf("first message in another conversation has seq %d", message.Seq)
+	}
+
+	var conversation models.Conversation
+	database.DB.Where("id = ?", first.ID).First(&conversation)
+	if conversation.LastSeq != 3 {
+		t.Errorf("last_seq = %d, want 3", conversation.LastSeq)
+	}
+
+	if _, err := SaveMessage("missing", "alice", "user", "hi"); err == nil {
+		t.Error("SaveMessage() into a missing conversation succeeded")
+	}
+}
+
+// 并发写入同一个会话时 seq 不重复、不跳号
+func TestSaveMessageConcurrentSeq(t *testing.T) {
+	dbtest.Setup(t)
+	conversation, err := GetOrCreateConversation("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers, perWriter = 4, 10
+	var (
+		mu   sync.Mutex
+		seqs []int64
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				message, err := SaveMessage(conversation.ID, "alice", "user", "hi")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				seqs = append(seqs, message.Seq)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(seqs)
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("seqs = %v, want 1..%d", seqs, writers*perWriter)
+		}
+	}
+}
+
+func TestSyncMessages(t *testing.T) {
+	dbtest.Setup(t)
+	conversation, err := GetOrCreateConversation("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveMessages(t, conversation.ID, "alice", 7)
+
+	// 按游标分页，直到 has_more 为 false
+	var (
+		cursor int64
+		pages  [][]int64
+	)
+	for {
+		result, err := SyncMessages(conversation.ID, "bob", cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []int64
+		for _, message := range result.Messages {
+			page = append(page, message.Seq)
+		}
+		pages = append(pages, page)
+		cursor = result.LastSeq
+		if !result.HasMore {
+			break
+		}
+	}
+	if got := fmt.Sprint(pages); got != "[[1 2 3] [4 5 6] [7]]" {
+		t.Fatalf("pages = %s", got)
+	}
+
+	// 已经同步到最新时返回空列表，游标保持不变
+	result, err := SyncMessages(conversation.ID, "bob", cursor, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Messages) != 0 || result.HasMore || result.LastSeq != 7 {
+		t.Fatalf("sync at head = %+v", result)
+	}
+
+	// 恰好取满一页时不应报告还有更多
+	result, err = SyncMessages(conversation.ID, "bob", 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Messages) != 3 || result.HasMore || result.LastSeq != 7 {
+		t.Fatalf("exact page = %d messages, has_more %v, last_seq %d", len(result.Messages), result.HasMore, result.LastSeq)
+	}
+
+	if _, err := SyncMessages(conversation.ID, "mallory", 0, 3); !errors.Is(err, ErrNotMember) {
+		t.Fatalf("non-member sync = %v, want ErrNotMember", err)
+	}
+}
+
+func TestSyncMessagesLimit(t *testing.T) {
+	dbtest.Setup(t)
+	conversation, err := GetOrCreateConversation("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveMessages(t, conversation.ID, "alice", defaultSyncLimit+1)
+
+	for _, limit := range []int{0, -1} {
+		result, err := SyncMessages(conversation.ID, "alice", 0, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Messages) != defaultSyncLimit || !result.HasMore {
+			t.Errorf("limit %d: %d messages, has_more %v", limit, len(result.Messages), result.HasMore)
+		}
+	}
+}
+
+func TestSyncConversationHandler(t *testing.T) {
+	dbtest.Setup(t)
+	conversation, err := GetOrCreateConversation("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveMessages(t, conversation.ID, "alice", 2)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/conversations/:id/sync", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+	}, SyncConversation)
+
+	for _, tt := range []struct {
+		user   string
+		query  string
+		status int
+	}{
+		{user: "bob", query: "after_seq=1", status: http.StatusOK},
+		{user: "bob", status: http.StatusOK},
+		{user: "bob", query: "after_seq=-1", status: http.StatusBadRequest},
+		{user: "bob", query: "after_seq=x", status: http.StatusBadRequest},
+		{user: "mallory", status: http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/conversations/"+conversation.ID+"/sync?"+tt.query, nil)
+		req.Header.Set("X-User", tt.user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s ?%s: status = %d, want %d", tt.user, tt.query, w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey 用户为脚本和机器人创建的个人访问令牌，只保存哈希，Prefix 用于在列表中辨认
+type APIKey struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID     string     `json:"user_id" gorm:"index;size:36"`
+	Name       string     `json:"name" gorm:"size:100"`
+	Prefix     string     `json:"prefix" gorm:"size:16"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;size:64"`
+	Scopes     string     `json:"scopes" gorm:"size:255"` // 空格分隔
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"size:45"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditEvent 安全相关事件（登录失败、账号锁定等）的审计记录
+type AuditEvent struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:36"`
+	Type      string    `json:"type" gorm:"index;size:50"`
+	UserID    string    `json:"user_id" gorm:"index;size:36"`
+	Username  string    `json:"username" gorm:"size:50"`
+	IP        string    `json:"ip" gorm:"index;size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	Detail    string    `json:"detail" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type AuthorizationCode struct {
+	CodeHash            string     `json:"-" gorm:"primaryKey;size:64"`
+	ClientID            string     `json:"client_id" gorm:"index;size:36"`
+	UserID              string     `json:"user_id" gorm:"size:36"`
+	RedirectURI         string     `json:"redirect_uri" gorm:"type:text"`
+	Scope               string     `json:"scope" gorm:"size:255"`
+	CodeChallenge       string     `json:"-" gorm:"size:128"`
+	CodeChallengeMethod string     `json:"-" gorm:"size:10"`
+	Nonce               string     `json:"-" gorm:"size:255"`
+	AuthTime            time.Time  `json:"auth_time"`
+	FamilyID            string     `json:"family_id" gorm:"size:36"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+	UsedAt              *time.Time `json:"used_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func (AuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Client 接入 IM 的第三方应用，RedirectURIs 和 Scopes 均以空格分隔。
+// Public 客户端（SPA、移动端）无法保存 client_secret，必须使用 PKCE。
+type Client struct {
+	ID           string    `json:"client_id" gorm:"primaryKey;size:36"`
+	SecretHash   string    `json:"-" gorm:"size:255"`
+	Name         string    `json:"name" gorm:"size:100"`
+	RedirectURIs string    `json:"redirect_uris" gorm:"type:text"`
+	Scopes       string    `json:"scopes" gorm:"size:255"`
+	Public       bool      `json:"public"`
+	OwnerID      string    `json:"owner_id" gorm:"index;size:36"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (Client) TableName() string {
+	return "oauth_clients"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DeviceCode RFC 8628 设备授权。设备用 device_code 轮询 token，用户在另一台设备上输入 user_code 批准
+type DeviceCode struct {
+	DeviceCodeHash string     `json:"-" gorm:"primaryKey;size:64"`
+	UserCode       string     `json:"user_code" gorm:"uniqueIndex;size:16"`
+	ClientID       string     `json:"client_id" gorm:"index;size:36"`
+	Scope          string     `json:"scope" gorm:"size:255"`
+	UserID         string     `json:"user_id" gorm:"size:36"`
+	Status         string     `json:"status" gorm:"size:20;default:'pending'"` // pending, approved, denied
+	Interval       int        `json:"interval"`
+	AuthTime       *time.Time `json:"auth_time,omitempty"`
+	LastPolledAt   *time.Time `json:"last_polled_at,omitempty"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (DeviceCode) TableName() string {
+	return "oauth_device_codes"
+}
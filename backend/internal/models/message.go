@@ -4,7 +4,8 @@ import "time"
 
 type Message struct {
 	ID             string    `json:"id" gorm:"primaryKey;size:36"`
-	ConversationID string    `json:"conversation_id" gorm:"index;size:36"`
+	ConversationID string    `json:"conversation_id" gorm:"index;index:idx_messages_conversation_seq,priority:1;size:36"`
+	Seq            int64     `json:"seq" gorm:"index:idx_messages_conversation_seq,priority:2;default:0"`
 	SenderID       string    `json:"sender_id" gorm:"size:36"`
 	SenderType     string    `json:"sender_type" gorm:"size:20"`
 	ContentType    string    `json:"content_type" gorm:"size:20"`
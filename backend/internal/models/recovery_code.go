@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RecoveryCode 两步验证的一次性恢复码，只保存哈希
+type RecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID    string     `json:"user_id" gorm:"index;size:36"`
+	CodeHash  string     `json:"-" gorm:"size:64"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
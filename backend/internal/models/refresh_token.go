@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID     string     `json:"user_id" gorm:"index;size:36"`
+	FamilyID   string     `json:"family_id" gorm:"index;size:36"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;size:64"`
+	DeviceID   string     `json:"device_id" gorm:"size:64"`
+	SessionID  string     `json:"session_id" gorm:"index;size:36"`
+	ClientID   string     `json:"client_id" gorm:"index;size:36"`
+	Scope      string     `json:"scope" gorm:"size:255"`
+	ReplacedBy string     `json:"replaced_by" gorm:"size:36"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session 一次登录对应的设备会话，access token 通过 sid 引用
+type Session struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID       string     `json:"user_id" gorm:"index;size:36"`
+	DeviceID     string     `json:"device_id" gorm:"size:64"`
+	DeviceName   string     `json:"device_name" gorm:"size:100"`
+	Platform     string     `json:"platform" gorm:"size:20"`
+	IP           string     `json:"ip" gorm:"size:45"`
+	UserAgent    string     `json:"user_agent" gorm:"size:255"`
+	LastActiveAt time.Time  `json:"last_active_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
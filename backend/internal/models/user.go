@@ -2,12 +2,21 @@ package models
 
 import "time"
 
+const (
+	UserStatusPending = "pending" // 已注册，邮箱尚未验证
+	UserStatusActive  = "active"
+)
+
 type User struct {
 	ID           string    `json:"id" gorm:"primaryKey;size:36"`
 	Username     string    `json:"username" gorm:"uniqueIndex;size:50"`
 	PasswordHash string    `json:"-" gorm:"size:255"`
 	Email        string    `json:"email" gorm:"size:100"`
 	Status       string    `json:"status" gorm:"size:20;default:'active'"`
+	AuthProvider string    `json:"-" gorm:"size:20;default:'local'"` // local、ldap
+	TOTPSecret   string    `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPEnabled  bool      `json:"-" gorm:"column:totp_enabled"` // 只通过 /2fa 返回给本人
+	TOTPLastStep int64     `json:"-" gorm:"column:totp_last_step"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
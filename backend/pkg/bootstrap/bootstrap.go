@@ -3,9 +3,18 @@ package bootstrap
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cyperlo/im/internal/auth"
 	"github.com/cyperlo/im/pkg/database"
+	"github.com/cyperlo/im/pkg/jwt"
+	"github.com/cyperlo/im/pkg/mailer"
+	"github.com/cyperlo/im/pkg/ratelimit"
 	"github.com/cyperlo/im/pkg/redis"
+	"github.com/cyperlo/im/pkg/revocation"
+	"github.com/cyperlo/im/pkg/websocket"
 )
 
 func InitAll() error {
@@ -13,9 +22,20 @@ func InitAll() error {
 		return err
 	}
 
-	// if err := InitRedis(); err != nil {
-	// 	return err
-	// }
+	if err := InitJWT(); err != nil {
+		return err
+	}
+
+	if err := InitMailer(); err != nil {
+		return err
+	}
+
+	// Redis 不可用时各组件退回进程内实现，单实例部署仍可运行
+	if err := InitRedis(); err != nil {
+		log.Printf("Redis unavailable, falling back to in-memory stores: %v", err)
+	}
+	revocation.Init(redis.Client)
+	ratelimit.Init(redis.Client)
 
 	log.Println("All services initialized successfully")
 	return nil
@@ -44,9 +64,102 @@ func InitRedis() error {
 	return redis.Init(config)
 }
 
+func InitJWT() error {
+	return jwt.Init(jwt.Config{
+		Secret:         getEnv("JWT_SECRET", ""),
+		Issuer:         getEnv("OIDC_ISSUER", ""),
+		KeysDir:        getEnv("JWT_KEYS_DIR", ""),
+		SigningKeyID:   getEnv("JWT_SIGNING_KID", ""),
+		AccessTokenTTL: getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+	})
+}
+
+func InitMailer() error {
+	return mailer.Init(mailer.Config{
+		Driver:      getEnv("MAIL_DRIVER", "log"),
+		SMTPHost:    getEnv("SMTP_HOST", ""),
+		SMTPPort:    getEnvInt("SMTP_PORT", 587),
+		Username:    getEnv("SMTP_USERNAME", ""),
+		Password:    getEnv("SMTP_PASSWORD", ""),
+		From:        getEnv("MAIL_FROM", ""),
+		Dir:         getEnv("MAIL_DIR", ""),
+		LinkBaseURL: getEnv("MAIL_LINK_BASE_URL", ""),
+	})
+}
+
+// InitIdentityProviders 组装登录认证链，只有认证服务需要调用
+func InitIdentityProviders() error {
+	return auth.InitIdentityProviders(auth.IdentityConfig{
+		Providers: strings.Split(getEnv("AUTH_PROVIDERS", auth.ProviderLocal), ","),
+		LDAP: auth.LDAPConfig{
+			URL:                getEnv("LDAP_URL", ""),
+			StartTLS:           getEnv("LDAP_START_TLS", "false") == "true",
+			InsecureSkipVerify: getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+			UserDNTemplate:     getEnv("LDAP_USER_DN_TEMPLATE", ""),
+			BindDN:             getEnv("LDAP_BIND_DN", ""),
+			BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             getEnv("LDAP_BASE_DN", ""),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			Timeout:            getEnvDuration("LDAP_TIMEOUT", 5*time.Second),
+		},
+	})
+}
+
+// InitWebSocket 设置 WebSocket 心跳、超时、发送队列参数以及跨节点转发，只有网关需要调用，须在 InitAll 之后
+func InitWebSocket() error {
+	if err := websocket.Init(websocket.Config{
+		PingInterval:       getEnvDuration("WS_PING_INTERVAL", 25*time.Second),
+		PongWait:           getEnvDuration("WS_PONG_WAIT", 60*time.Second),
+		WriteWait:          getEnvDuration("WS_WRITE_WAIT", 10*time.Second),
+		AckTimeout:         getEnvDuration("WS_ACK_TIMEOUT", 5*time.Second),
+		MaxRedeliveries:    getEnvInt("WS_MAX_REDELIVERIES", 5),
+		SendBuffer:         getEnvInt("WS_SEND_BUFFER", 256),
+		SlowConsumerPolicy: websocket.SlowConsumerPolicy(getEnv("WS_SLOW_CONSUMER_POLICY", string(websocket.PolicyCoalesce))),
+		MaxPendingPerConn:  getEnvInt("WS_MAX_PENDING", 1024),
+	}); err != nil {
+		return err
+	}
+
+	websocket.InitCluster(redis.Client, getEnv("NODE_ID", ""), getEnvDuration("WS_REGISTRY_TTL", 2*time.Minute))
+	return nil
+}
+
+// TrustedProxies 读取 TRUSTED_PROXIES（逗号分隔的 IP 或 CIDR），只应包含前面的网关或负载均衡。
+// 默认不信任任何代理，ClientIP 直接取 TCP 对端地址，客户端伪造的 X-Forwarded-For 不会生效
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("Invalid integer for %s: %s, using default %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %s, using default %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
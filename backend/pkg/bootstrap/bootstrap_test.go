@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{env: "", want: nil},
+		{env: " , ", want: nil},
+		{env: "10.0.0.1", want: []string{"10.0.0.1"}},
+		{env: "10.0.0.1, 172.16.0.0/12 ,", want: []string{"10.0.0.1", "172.16.0.0/12"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("TRUSTED_PROXIES", tt.env)
+		if got := TrustedProxies(); !slices.Equal(got, tt.want) {
+			t.Errorf("TrustedProxies() with %q = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		env    string
+		remote string
+		want   string
+	}{
+		{name: "no proxies", env: "", remote: "203.0.113.7:40000", want: "203.0.113.7"},
+		{name: "untrusted peer", env: "10.0.0.1", remote: "203.0.113.7:40000", want: "203.0.113.7"},
+		{name: "trusted gateway", env: "10.0.0.0/8", remote: "10.0.0.1:40000", want: "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.env)
+			r := gin.New()
+			if err := r.SetTrustedProxies(TrustedProxies()); err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			r.GET("/", func(c *gin.Context) { got = c.ClientIP() })
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "198.51.100.9")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
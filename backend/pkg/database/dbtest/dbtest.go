@@ -0,0 +1,29 @@
+// Package dbtest 为测试准备一个迁移好的 SQLite 数据库并替换 database.DB，只应在 _test.go 中引用
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cyperlo/im/pkg/database"
+	"gorm.io/driver/sqlite"
+)
+
+// Setup 每个测试使用独立的临时数据库，测试结束后恢复原来的 database.DB
+func Setup(t testing.TB) {
+	t.Helper()
+
+	old := database.DB
+	dsn := filepath.Join(t.TempDir(), "im.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	if err := database.Open(sqlite.Open(dsn)); err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		database.DB = old
+	})
+}
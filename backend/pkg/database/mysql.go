@@ -7,6 +7,7 @@ import (
 	"github.com/cyperlo/im/internal/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -29,8 +30,13 @@ func Init(config Config) error {
 		config.DBName,
 	)
 
+	return Open(mysql.Open(dsn))
+}
+
+// Open 用指定的驱动连接数据库并完成迁移，Init 使用 MySQL，测试使用 SQLite
+func Open(dialector gorm.Dialector) error {
 	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
@@ -41,6 +47,10 @@ func Init(config Config) error {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := backfillMessageSeq(); err != nil {
+		return fmt.Errorf("failed to backfill message seq: %w", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 	return nil
 }
@@ -52,5 +62,66 @@ func autoMigrate() error {
 		&models.Conversation{},
 		&models.ConversationMember{},
 		&models.Friend{},
+		&models.RefreshToken{},
+		&models.Client{},
+		&models.AuthorizationCode{},
+		&models.Session{},
+		&models.RecoveryCode{},
+		&models.AuditEvent{},
+		&models.APIKey{},
+		&models.DeviceCode{},
 	)
 }
+
+// backfillMessageSeq 为引入 seq 之前保存的消息按时间顺序补齐会话内序号。
+// 只处理 last_seq 仍为 0 的会话，已经分配过 seq 的会话不受影响，重复执行是安全的
+func backfillMessageSeq() error {
+	var conversationIDs []string
+	if err := DB.Model(&models.Message{}).
+		Distinct("conversation_id").
+		Where("seq = 0").
+		Pluck("conversation_id", &conversationIDs).Error; err != nil {
+		return err
+	}
+
+	filled := 0
+	for _, conversationID := range conversationIDs {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var conversation models.Conversation
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND last_seq = 0", conversationID).
+				First(&conversation).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return nil
+				}
+				return err
+			}
+
+			var messageIDs []string
+			if err := tx.Model(&models.Message{}).
+				Where("conversation_id = ?", conversationID).
+				Order("created_at ASC, id ASC").
+				Pluck("id", &messageIDs).Error; err != nil {
+				return err
+			}
+
+			for i, id := range messageIDs {
+				if err := tx.Model(&models.Message{}).Where("id = ?", id).
+					UpdateColumn("seq", i+1).Error; err != nil {
+					return err
+				}
+			}
+			filled++
+			return tx.Model(&models.Conversation{}).Where("id = ?", conversationID).
+				UpdateColumn("last_seq", len(messageIDs)).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if filled > 0 {
+		log.Printf("Backfilled message seq for %d conversations", filled)
+	}
+	return nil
+}
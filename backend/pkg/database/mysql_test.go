@@ -0,0 +1,75 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/internal/models"
+	"github.com/cyperlo/im/pkg/database"
+	"gorm.io/driver/sqlite"
+)
+
+// 引入 seq 之前保存的消息在启动时按时间顺序补齐序号，已经分配过 seq 的会话保持不变
+func TestOpenBackfillsMessageSeq(t *testing.T) {
+	old := database.DB
+	t.Cleanup(func() { database.DB = old })
+
+	dsn := filepath.Join(t.TempDir(), "im.db") + "?_busy_timeout=5000"
+	if err := database.Open(sqlite.Open(dsn)); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	conversations := []models.Conversation{
+		{ID: "legacy", Type: "single", CreatedAt: base, UpdatedAt: base},
+		{ID: "current", Type: "single", LastSeq: 2, CreatedAt: base, UpdatedAt: base},
+	}
+	messages := []models.Message{
+		{ID: "m3", ConversationID: "legacy", Content: "third", CreatedAt: base.Add(3 * time.Minute)},
+		{ID: "m1", ConversationID: "legacy", Content: "first", CreatedAt: base.Add(time.Minute)},
+		{ID: "m2b", ConversationID: "legacy", Content: "second b", CreatedAt: base.Add(2 * time.Minute)},
+		{ID: "m2a", ConversationID: "legacy", Content: "second a", CreatedAt: base.Add(2 * time.Minute)},
+		{ID: "c1", ConversationID: "current", Content: "one", Seq: 1, CreatedAt: base},
+		{ID: "c2", ConversationID: "current", Content: "two", Seq: 2, CreatedAt: base},
+	}
+	if err := database.DB.Create(&conversations).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&messages).Error; err != nil {
+		t.Fatal(err)
+	}
+	closeDB(t)
+
+	// 再次启动时补齐，重复启动不会改变已有的序号
+	for i := 0; i < 2; i++ {
+		if err := database.Open(sqlite.Open(dsn)); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]int64{"m1": 1, "m2a": 2, "m2b": 3, "m3": 4, "c1": 1, "c2": 2}
+		var stored []models.Message
+		database.DB.Find(&stored)
+		for _, message := range stored {
+			if message.Seq != want[message.ID] {
+				t.Errorf("open %d: message %s seq = %d, want %d", i, message.ID, message.Seq, want[message.ID])
+			}
+		}
+
+		var legacy models.Conversation
+		database.DB.Where("id = ?", "legacy").First(&legacy)
+		if legacy.LastSeq != 4 {
+			t.Errorf("open %d: legacy last_seq = %d, want 4", i, legacy.LastSeq)
+		}
+		closeDB(t)
+	}
+}
+
+func closeDB(t *testing.T) {
+	t.Helper()
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+}
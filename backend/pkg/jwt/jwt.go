@@ -1,43 +1,199 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var secretKey = []byte("your-secret-key")
+var (
+	// secretKey 没有默认值：内置的密钥是公开的，任何人都能用它伪造 token
+	secretKey      []byte
+	accessTokenTTL = 15 * time.Minute
+	issuer         = "http://localhost:8081"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrNoKeyMaterial 既没有配置密钥目录也没有配置 HS256 密钥
+	ErrNoKeyMaterial = errors.New("no jwt key material configured, set JWT_KEYS_DIR or JWT_SECRET")
+)
+
+// Config 中配置了 KeysDir 时使用非对称密钥（RS256/EdDSA）签名，否则退回 HS256 + Secret，两者都没有时拒绝启动
+type Config struct {
+	Secret         string
+	Issuer         string
+	KeysDir        string
+	SigningKeyID   string
+	AccessTokenTTL time.Duration
+}
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string) (string, error) {
-	claims := Claims{
-		UserID: userID,
+// IDTokenClaims OpenID Connect ID token 的标准声明
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	AuthTime          int64  `json:"auth_time,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// PurposeClaims 一次性用途的 token（如两步登录的挑战），不能当作 access token 使用
+type PurposeClaims struct {
+	Purpose string            `json:"purpose"`
+	Data    map[string]string `json:"data,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IsApp 表示 token 由 client_credentials 签发，代表应用本身而不是某个用户
+func (c *Claims) IsApp() bool {
+	return c.UserID == "" && c.ClientID != ""
+}
+
+// HasScopes 判断 token 是否包含全部指定的 scope
+func (c *Claims) HasScopes(scopes ...string) bool {
+	granted := strings.Fields(c.Scope)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func Init(config Config) error {
+	if config.Secret != "" {
+		secretKey = []byte(config.Secret)
+	}
+	if config.AccessTokenTTL > 0 {
+		accessTokenTTL = config.AccessTokenTTL
+	}
+	if config.Issuer != "" {
+		issuer = strings.TrimSuffix(config.Issuer, "/")
+	}
+
+	if config.KeysDir == "" {
+		if len(secretKey) == 0 {
+			return ErrNoKeyMaterial
+		}
+		log.Println("JWT_KEYS_DIR not set, falling back to HS256 shared secret")
+		return nil
+	}
+
+	ks, err := LoadKeySet(config.KeysDir, config.SigningKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to load jwt keys: %w", err)
+	}
+	keySet = ks
+
+	if ks.active != nil {
+		log.Printf("JWT keys loaded: %d keys, signing with kid=%s", len(ks.keys), ks.active.ID)
+	} else {
+		log.Printf("JWT keys loaded: %d keys, verify only", len(ks.keys))
+	}
+	return nil
+}
+
+// Issuer 返回 token 中的 iss，同时也是 OIDC discovery 的基础地址
+func Issuer() string {
+	return issuer
+}
+
+// AccessTokenTTL 返回 access token 的有效期
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// GenerateClientToken 签发代表第三方应用的 access token，scope 以空格分隔
+func GenerateClientToken(userID, clientID, scope string) (string, error) {
+	return GenerateAccessToken(Claims{UserID: userID, ClientID: clientID, Scope: scope})
+}
+
+// GenerateAccessToken 按 claims 中的主体签发 access token，jti、iss、sub 和有效期由此处填充
+func GenerateAccessToken(claims Claims) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    issuer,
+		Subject:   claims.UserID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	if claims.IsApp() {
+		claims.Subject = claims.ClientID
+	}
+
+	return sign(claims)
+}
+
+// GenerateIDToken 签发 ID token，aud 为 clientID，有效期与 access token 一致
+func GenerateIDToken(subject, clientID string, claims IDTokenClaims) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    issuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{clientID},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+
+	return sign(claims)
+}
+
+// GeneratePurposeToken 签发只能用于 purpose 的短期 token，data 中存放该流程需要带到下一步的参数
+func GeneratePurposeToken(subject, purpose string, ttl time.Duration, data map[string]string) (string, error) {
+	claims := PurposeClaims{
+		Purpose: purpose,
+		Data:    data,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        uuid.New().String(),
+			Issuer:    issuer,
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	return sign(claims)
+}
+
+// ValidatePurposeToken 校验 token 并确认其用途与 purpose 一致
+func ValidatePurposeToken(tokenString, purpose string) (*PurposeClaims, error) {
+	token, err := parse(tokenString, &PurposeClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*PurposeClaims); ok && token.Valid && claims.Purpose == purpose && claims.Subject != "" {
+		return claims, nil
+	}
+
+	return nil, ErrInvalidToken
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
-	})
+	token, err := parse(tokenString, &Claims{})
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// ID token 等不带 user_id/client_id 的 token 不能当作 access token 使用
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && (claims.UserID != "" || claims.ClientID != "") {
 		return claims, nil
 	}
 
-	return nil, err
+	return nil, ErrInvalidToken
 }
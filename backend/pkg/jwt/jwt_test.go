@@ -0,0 +1,246 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// resetKeys 清空包级密钥配置，测试结束后恢复
+func resetKeys(t *testing.T) {
+	t.Helper()
+	oldSecret, oldKeySet, oldIssuer, oldTTL := secretKey, keySet, issuer, accessTokenTTL
+	secretKey, keySet = nil, nil
+	t.Cleanup(func() {
+		secretKey, keySet, issuer, accessTokenTTL = oldSecret, oldKeySet, oldIssuer, oldTTL
+	})
+}
+
+func writeKey(t *testing.T, dir, kid string, key interface{}, public bool) {
+	t.Helper()
+	var (
+		block *pem.Block
+		der   []byte
+		err   error
+	)
+	if public {
+		der, err = x509.MarshalPKIXPublicKey(key)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInitRequiresKeyMaterial(t *testing.T) {
+	resetKeys(t)
+
+	if err := Init(Config{}); !errors.Is(err, ErrNoKeyMaterial) {
+		t.Fatalf("Init() without keys = %v, want ErrNoKeyMaterial", err)
+	}
+	if _, err := GenerateAccessToken(Claims{UserID: "user-1"}); !errors.Is(err, ErrNoKeyMaterial) {
+		t.Fatalf("GenerateAccessToken() without keys = %v, want ErrNoKeyMaterial", err)
+	}
+
+	// 以前内置的默认密钥签出的 token 不能通过校验
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:           "admin",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString([]byte("your-secret-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(forged); err == nil {
+		t.Fatal("token signed with the old built-in secret was accepted")
+	}
+}
+
+func TestHS256Secret(t *testing.T) {
+	resetKeys(t)
+	if err := Init(Config{Secret: "test-secret", Issuer: "https://auth.example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := GenerateAccessToken(Claims{UserID: "user-1", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "user-1" || claims.SessionID != "session-1" || claims.Subject != "user-1" || claims.Issuer != "https://auth.example.com" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	secretKey = []byte("other-secret")
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("token accepted with a different secret")
+	}
+}
+
+// 共用同一密钥的其他服务签发的 token 不能在这里使用
+func TestValidateTokenRequiresIssuer(t *testing.T) {
+	resetKeys(t)
+	if err := Init(Config{Secret: "test-secret", Issuer: "https://other.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := GeneratePurposeToken("user-1", "mfa", time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Init(Config{Secret: "test-secret", Issuer: "https://auth.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("access token from another issuer was accepted")
+	}
+	if _, err := ValidatePurposeToken(challenge, "mfa"); err == nil {
+		t.Error("purpose token from another issuer was accepted")
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	resetKeys(t)
+
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeKey(t, dir, "2024-01", oldKey, false)
+	if err := Init(Config{KeysDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新密钥加入后按 kid 字典序成为签名密钥，旧 token 仍能验签
+	writeKey(t, dir, "2024-02", newKey, false)
+	if err := Init(Config{KeysDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		token string
+		kid   string
+		alg   string
+	}{
+		{token: oldToken, kid: "2024-01", alg: "EdDSA"},
+		{token: newToken, kid: "2024-02", alg: "RS256"},
+	} {
+		parsed, _, err := jwt.NewParser().ParseUnverified(tt.token, &Claims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Header["kid"] != tt.kid || parsed.Method.Alg() != tt.alg {
+			t.Errorf("token header = %v, want kid %s alg %s", parsed.Header, tt.kid, tt.alg)
+		}
+		if _, err := ValidateToken(tt.token); err != nil {
+			t.Errorf("ValidateToken(kid %s) = %v", tt.kid, err)
+		}
+	}
+
+	if jwks := PublicJWKS(); len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "OKP" || jwks.Keys[1].Kty != "RSA" {
+		t.Errorf("PublicJWKS() = %+v", jwks)
+	}
+
+	// 只持有公钥的网关可以验签但不能签发
+	verifyDir := t.TempDir()
+	writeKey(t, verifyDir, "2024-02", &newKey.PublicKey, true)
+	if err := Init(Config{KeysDir: verifyDir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(newToken); err != nil {
+		t.Errorf("verify-only ValidateToken() = %v", err)
+	}
+	if _, err := ValidateToken(oldToken); err == nil {
+		t.Error("token with a removed kid was accepted")
+	}
+	if _, err := GenerateAccessToken(Claims{UserID: "user-1"}); err == nil {
+		t.Error("verify-only key set signed a token")
+	}
+}
+
+// 配置了非对称密钥后不再接受 HS256，防止用公钥当作 HMAC 密钥伪造 token
+func TestKeySetRejectsHS256(t *testing.T) {
+	resetKeys(t)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeKey(t, dir, "k1", key, false)
+	if err := Init(Config{KeysDir: dir, Secret: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:           "user-1",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString([]byte("shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(signed); err == nil || !strings.Contains(err.Error(), "signing method") {
+		t.Fatalf("ValidateToken(HS256) = %v, want signing method error", err)
+	}
+}
+
+func TestPurposeToken(t *testing.T) {
+	resetKeys(t)
+	if err := Init(Config{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := GeneratePurposeToken("user-1", "mfa", time.Minute, map[string]string{"session": "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidatePurposeToken(token, "mfa")
+	if err != nil || claims.Data["session"] != "s1" {
+		t.Fatalf("ValidatePurposeToken() = %+v, %v", claims, err)
+	}
+	if _, err := ValidatePurposeToken(token, "reset"); err == nil {
+		t.Error("purpose token accepted for another purpose")
+	}
+	// 没有 user_id 的一次性 token 不能当作 access token
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("purpose token accepted as access token")
+	}
+}
@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keySet 为 nil 时退回到 HS256 + secretKey
+var keySet *KeySet
+
+type signingKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet 持有用于签名和验签的非对称密钥。轮换时新旧密钥同时存在：
+// 新 token 用 active 密钥签名，旧 token 在过期前仍能用旧公钥验签。
+type KeySet struct {
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// LoadKeySet 读取 dir 下的 <kid>.pem 文件，支持 RSA 和 Ed25519 的私钥或公钥。
+// 只有公钥的服务（如网关）只能验签。activeKID 为空时使用 kid 字典序最大的私钥签名。
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: make(map[string]*signingKey)}
+	var signers []string
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadKey(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		ks.keys[kid] = key
+		if key.Private != nil {
+			signers = append(signers, kid)
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", dir)
+	}
+
+	if activeKID == "" && len(signers) > 0 {
+		sort.Strings(signers)
+		activeKID = signers[len(signers)-1]
+	}
+	if activeKID != "" {
+		key, ok := ks.keys[activeKID]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("signing key %s not found", activeKID)
+		}
+		ks.active = key
+	}
+
+	return ks, nil
+}
+
+// PublicJWKS 返回所有用于验签的公钥，供 /.well-known/jwks.json 发布
+func PublicJWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if keySet == nil {
+		return set
+	}
+
+	kids := make([]string, 0, len(keySet.keys))
+	for kid := range keySet.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := keySet.keys[kid]
+		jwk := JSONWebKey{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// SigningAlgorithms 返回当前可能出现在 token 中的签名算法
+func SigningAlgorithms() []string {
+	if keySet == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	var algs []string
+	for _, key := range keySet.keys {
+		if !slices.Contains(algs, key.Method.Alg()) {
+			algs = append(algs, key.Method.Alg())
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+func sign(claims jwt.Claims) (string, error) {
+	if keySet == nil {
+		if len(secretKey) == 0 {
+			return "", ErrNoKeyMaterial
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey)
+	}
+	if keySet.active == nil {
+		return "", errors.New("no signing key configured")
+	}
+
+	token := jwt.NewWithClaims(keySet.active.Method, claims)
+	token.Header["kid"] = keySet.active.ID
+	return token.SignedString(keySet.active.Private)
+}
+
+func parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	// 只接受本服务签发的 token，其他使用同一密钥的服务签发的 token 不能混用
+	if keySet == nil {
+		return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if len(secretKey) == 0 {
+				return nil, ErrNoKeyMaterial
+			}
+			return secretKey, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(issuer))
+	}
+
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keySet.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(issuer))
+}
+
+func loadKey(kid, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if pub, ok := key.Public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,77 @@
+// Package mailer 发送系统邮件（邮箱验证、密码重置等）。生产环境使用 SMTP，
+// 本地开发可以把邮件写到目录或日志里。
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config Driver 为 smtp、file 或 log，file 会把邮件写到 Dir 下
+type Config struct {
+	Driver      string
+	SMTPHost    string
+	SMTPPort    int
+	Username    string
+	Password    string
+	From        string
+	Dir         string
+	LinkBaseURL string
+}
+
+var (
+	defaultMailer Mailer = &FileMailer{}
+	linkBaseURL          = "http://localhost:3000"
+)
+
+func Init(config Config) error {
+	if config.LinkBaseURL != "" {
+		linkBaseURL = strings.TrimSuffix(config.LinkBaseURL, "/")
+	}
+
+	switch config.Driver {
+	case "smtp":
+		if config.SMTPHost == "" || config.From == "" {
+			return fmt.Errorf("smtp mailer requires host and from address")
+		}
+		defaultMailer = &SMTPMailer{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.Username,
+			Password: config.Password,
+			From:     config.From,
+		}
+		log.Printf("Mailer using SMTP %s:%d", config.SMTPHost, config.SMTPPort)
+	case "file":
+		defaultMailer = &FileMailer{Dir: config.Dir}
+		log.Printf("Mailer writing messages to %s", config.Dir)
+	case "", "log":
+		defaultMailer = &FileMailer{}
+		log.Println("Mailer writing messages to log")
+	default:
+		return fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+	return nil
+}
+
+// Send 使用 Init 配置的实现发送邮件
+func Send(ctx context.Context, msg Message) error {
+	return defaultMailer.Send(ctx, msg)
+}
+
+// Link 拼接邮件中指向前端页面的链接
+func Link(path string) string {
+	return linkBaseURL + path
+}
@@ -0,0 +1,109 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// 通过 Sec-WebSocket-Protocol 协商的子协议。未协商子协议的连接使用旧格式
+const (
+	SubprotocolJSON  = "im.json.v1"
+	SubprotocolProto = "im.proto.v1"
+)
+
+// Subprotocols 服务端支持的子协议，按优先级排列
+var Subprotocols = []string{SubprotocolProto, SubprotocolJSON}
+
+// Codec 连接上帧的编解码方式
+type Codec interface {
+	// Name 协商出的子协议，旧格式为空
+	Name() string
+	// Binary 为 true 时以二进制 WebSocket 帧发送
+	Binary() bool
+	Encode(env *Envelope) ([]byte, error)
+	Decode(data []byte) (*Envelope, error)
+}
+
+var (
+	Legacy Codec = legacyCodec{}
+	JSON   Codec = jsonCodec{}
+	Proto  Codec = protoCodec{}
+)
+
+// CodecFor 按协商出的子协议选择编解码器
+func CodecFor(subprotocol string) Codec {
+	switch subprotocol {
+	case SubprotocolJSON:
+		return JSON
+	case SubprotocolProto:
+		return Proto
+	default:
+		return Legacy
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return SubprotocolJSON }
+
+func (jsonCodec) Binary() bool { return false }
+
+func (jsonCodec) Encode(env *Envelope) ([]byte, error) {
+	return json.Marshal(env)
+}
+
+func (jsonCodec) Decode(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// legacyCodec 旧客户端不认识信封：推送把 type、delivery_id 与 payload 的字段平铺在同一个对象里；
+// 对 v1 请求的回复（带 req_id）仍使用信封
+type legacyCodec struct{}
+
+func (legacyCodec) Name() string { return "" }
+
+func (legacyCodec) Binary() bool { return false }
+
+func (legacyCodec) Encode(env *Envelope) ([]byte, error) {
+	if env.ReqID != "" {
+		return json.Marshal(env)
+	}
+
+	// 字符串必须按 JSON 规则转义，strconv.Quote 的 Go 转义（如 \x00、\a）不是合法 JSON
+	header := struct {
+		Type       string `json:"type"`
+		DeliveryID string `json:"delivery_id,omitempty"`
+	}{env.Type, env.DeliveryID}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := bytes.TrimSpace(env.Payload)
+	if len(payload) > 0 && !json.Valid(payload) {
+		return nil, fmt.Errorf("invalid %s payload", env.Type)
+	}
+
+	// 去掉 header 末尾的 }，接上 payload 对象的字段
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	if len(payload) >= 2 && payload[0] == '{' {
+		rest := bytes.TrimSpace(payload[1:])
+		if rest[0] != '}' {
+			buf.WriteByte(',')
+		}
+		buf.Write(rest)
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes(), nil
+}
+
+func (legacyCodec) Decode(data []byte) (*Envelope, error) {
+	return jsonCodec{}.Decode(data)
+}
@@ -0,0 +1,262 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cyperlo/im/pkg/protocol/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// 控制字符、引号、行分隔符和多字节字符，手工拼接 JSON 时最容易出错
+const trickyText = "hi \"there\"\\ \x00\a\b\f\n\r\t\x1f    </script> 你好 😀"
+
+// serverFrames 服务端会发出的每一种帧，字段都取非零值，protobuf 不会省略
+func serverFrames() []Envelope {
+	frames := []Envelope{
+		NewEnvelope(FrameChat, ChatMessage{
+			To: "bob", From: "alice-id", FromUsername: trickyText, SenderType: "user", Content: trickyText,
+			Timestamp: 1700000000, MessageID: "m1", ConversationID: "c1", Seq: 42,
+		}),
+		NewEnvelope(FrameGroupMessage, GroupMessage{
+			ConversationID: "g1", GroupName: trickyText, From: "alice-id", FromUsername: "alice",
+			Content: trickyText, MessageID: "m2", Seq: 7, Timestamp: 1700000001,
+		}),
+		{V: Version, Type: FrameSync, Payload: json.RawMessage(`{
+			"conversation_id": "c1",
+			"messages": [{
+				"id": "m1", "conversation_id": "c1", "seq": 41, "sender_id": "alice-id", "sender_type": "user",
+				"content_type": "text", "content": "` + jsonEscape(trickyText) + `", "status": "sent",
+				"created_at": "2024-01-01T00:00:00Z"
+			}],
+			"last_seq": 42,
+			"has_more": true
+		}`)},
+		NewEnvelope(FrameAck, AckReply{MessageID: "m1", ConversationID: "c1", Seq: 42}),
+		NewEnvelope(FrameError, ErrorReply{Code: ErrCodeBadRequest, Reason: trickyText}),
+		NewEnvelope(FramePong, nil),
+		NewEnvelope(FrameMessageRecalled, MessageRecalled{MessageID: "m1", SenderID: "alice-id", Content: trickyText, Timestamp: 1700000002}),
+		NewEnvelope(FrameGroupCreated, GroupCreated{ConversationID: "g1", GroupName: trickyText, Timestamp: 1700000003}),
+		NewEnvelope(FrameTyping, Typing{ConversationID: "c1", UserID: "alice-id"}),
+	}
+	for i := range frames {
+		frames[i].DeliveryID = "delivery-\x00\a-" + frames[i].Type
+	}
+	return frames
+}
+
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// decodeJSON 把 JSON 解成通用结构用于比较，缺失的 payload 视为空对象
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	if len(data) == 0 {
+		return map[string]interface{}{}
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	return v
+}
+
+func TestServerFramesCoverProtobufPayloads(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, env := range serverFrames() {
+		covered[env.Type] = true
+	}
+
+	fields := (&pb.ServerFrame{}).ProtoReflect().Descriptor().Oneofs().ByName("payload").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if name := string(fields.Get(i).Name()); !covered[name] {
+			t.Errorf("server frame %s has no round-trip case", name)
+		}
+	}
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	for _, env := range append(serverFrames(), withReqID(serverFrames())...) {
+		data, err := JSON.Encode(&env)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		got, err := JSON.Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		if got.V != env.V || got.Type != env.Type || got.ReqID != env.ReqID || got.DeliveryID != env.DeliveryID {
+			t.Errorf("%s: header = %+v, want %+v", env.Type, got, env)
+		}
+		if !reflect.DeepEqual(decodeJSON(t, got.Payload), decodeJSON(t, env.Payload)) {
+			t.Errorf("%s: payload = %s, want %s", env.Type, got.Payload, env.Payload)
+		}
+	}
+}
+
+func TestProtoCodecRoundTrip(t *testing.T) {
+	for _, env := range append(serverFrames(), withReqID(serverFrames())...) {
+		data, err := Proto.Encode(&env)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+
+		var frame pb.ServerFrame
+		if err := proto.Unmarshal(data, &frame); err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		if frame.V != Version || frame.Type != env.Type || frame.ReqId != env.ReqID || frame.DeliveryId != env.DeliveryID {
+			t.Errorf("%s: header = v=%d type=%q req_id=%q delivery_id=%q", env.Type, frame.V, frame.Type, frame.ReqId, frame.DeliveryId)
+		}
+
+		m := frame.ProtoReflect()
+		fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+		if fd == nil || string(fd.Name()) != env.Type {
+			t.Fatalf("%s: payload field = %v", env.Type, fd)
+		}
+		payload, err := json.Marshal(messageToMap(m.Get(fd).Message()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decodeJSON(t, payload), decodeJSON(t, env.Payload)) {
+			t.Errorf("%s: payload = %s, want %s", env.Type, payload, env.Payload)
+		}
+	}
+}
+
+func TestProtoCodecRejectsUnknownFrameType(t *testing.T) {
+	env := NewEnvelope("unknown", map[string]string{"a": "b"})
+	if _, err := Proto.Encode(&env); err == nil {
+		t.Fatal("Encode() of an unknown frame type succeeded")
+	}
+}
+
+func TestProtoCodecDecodeClientFrame(t *testing.T) {
+	data, err := proto.Marshal(&pb.ClientFrame{
+		V:       Version,
+		ReqId:   "r1",
+		Payload: &pb.ClientFrame_Chat{Chat: &pb.ChatRequest{To: "bob", Content: trickyText}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := Proto.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未设置 type 时按 payload 推断
+	if env.Type != FrameChat || env.ReqID != "r1" || env.V != Version {
+		t.Fatalf("Decode() = %+v", env)
+	}
+	var payload ChatPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || payload != (ChatPayload{To: "bob", Content: trickyText}) {
+		t.Fatalf("payload = %s (%v)", env.Payload, err)
+	}
+
+	mismatched, _ := proto.Marshal(&pb.ClientFrame{
+		V:       Version,
+		Type:    FrameAck,
+		Payload: &pb.ClientFrame_Chat{Chat: &pb.ChatRequest{To: "bob"}},
+	})
+	if _, err := Proto.Decode(mismatched); err == nil {
+		t.Fatal("Decode() accepted a payload that does not match the frame type")
+	}
+}
+
+// 旧格式的推送把 type、delivery_id 和 payload 的字段平铺在同一个对象里，必须是合法 JSON
+func TestLegacyCodecRoundTrip(t *testing.T) {
+	for _, env := range serverFrames() {
+		data, err := Legacy.Encode(&env)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		if !json.Valid(data) {
+			t.Fatalf("%s: invalid JSON %q", env.Type, data)
+		}
+
+		got := decodeJSON(t, data).(map[string]interface{})
+		if got["type"] != env.Type || got["delivery_id"] != env.DeliveryID {
+			t.Errorf("%s: type=%v delivery_id=%v", env.Type, got["type"], got["delivery_id"])
+		}
+		delete(got, "type")
+		delete(got, "delivery_id")
+		if want := decodeJSON(t, env.Payload); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: fields = %v, want %v", env.Type, got, want)
+		}
+	}
+}
+
+func TestLegacyCodecReplies(t *testing.T) {
+	// 对 v1 请求的回复带 req_id，使用信封
+	for _, env := range withReqID(serverFrames()) {
+		data, err := Legacy.Encode(&env)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		got, err := Legacy.Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", env.Type, err)
+		}
+		if got.ReqID != env.ReqID || got.Type != env.Type || !reflect.DeepEqual(decodeJSON(t, got.Payload), decodeJSON(t, env.Payload)) {
+			t.Errorf("%s: Decode() = %+v", env.Type, got)
+		}
+	}
+}
+
+func TestLegacyCodecEdgeCases(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     Envelope
+		want    string
+		wantErr bool
+	}{
+		{name: "no payload", env: Envelope{Type: FramePong}, want: `{"type":"pong"}`},
+		{name: "empty object", env: Envelope{Type: FramePong, Payload: json.RawMessage(` { } `)}, want: `{"type":"pong"}`},
+		{name: "no delivery id", env: Envelope{Type: FrameTyping, Payload: json.RawMessage(`{"user_id":"u"}`)}, want: `{"type":"typing","user_id":"u"}`},
+		{name: "non-object payload", env: Envelope{Type: FrameSync, Payload: json.RawMessage(`[1,2]`)}, want: `{"type":"sync"}`},
+		{name: "control characters in type", env: Envelope{Type: "a\x00b\ac", DeliveryID: "d\x7f"}, want: `{"type":"a\u0000b\u0007c","delivery_id":"d` + "\x7f" + `"}`},
+		{name: "invalid payload", env: Envelope{Type: FrameChat, Payload: json.RawMessage(`{"a":`)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Legacy.Encode(&tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Encode() = %s, want error", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want || !json.Valid(data) {
+				t.Errorf("Encode() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func withReqID(frames []Envelope) []Envelope {
+	for i := range frames {
+		frames[i].ReqID = "req-" + frames[i].Type
+		frames[i].DeliveryID = ""
+	}
+	return frames
+}
+
+func TestCodecFor(t *testing.T) {
+	for subprotocol, want := range map[string]Codec{
+		SubprotocolJSON:  JSON,
+		SubprotocolProto: Proto,
+		"":               Legacy,
+		"im.xml.v1":      Legacy,
+	} {
+		if got := CodecFor(subprotocol); got != want {
+			t.Errorf("CodecFor(%q) = %T", subprotocol, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cyperlo/im/pkg/protocol/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoCodec 信封的 payload 对应 ClientFrame/ServerFrame 中与 type 同名的 oneof 字段。
+// 业务代码只处理 JSON payload，这里在两种表示之间转换
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return SubprotocolProto }
+
+func (protoCodec) Binary() bool { return true }
+
+func (protoCodec) Encode(env *Envelope) ([]byte, error) {
+	frame := &pb.ServerFrame{
+		V:          Version,
+		Type:       env.Type,
+		ReqId:      env.ReqID,
+		DeliveryId: env.DeliveryID,
+	}
+
+	m := frame.ProtoReflect()
+	fd := payloadField(m, env.Type)
+	if fd == nil {
+		return nil, fmt.Errorf("no protobuf payload for frame type %q", env.Type)
+	}
+
+	value := m.NewField(fd)
+	if len(env.Payload) > 0 {
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(env.Payload, value.Message().Interface()); err != nil {
+			return nil, fmt.Errorf("encode %s payload: %w", env.Type, err)
+		}
+	}
+	m.Set(fd, value)
+
+	return proto.Marshal(frame)
+}
+
+func (protoCodec) Decode(data []byte) (*Envelope, error) {
+	var frame pb.ClientFrame
+	if err := proto.Unmarshal(data, &frame); err != nil {
+		return nil, err
+	}
+
+	env := &Envelope{V: int(frame.V), Type: frame.Type, ReqID: frame.ReqId}
+
+	m := frame.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if fd == nil {
+		return env, nil
+	}
+	if env.Type == "" {
+		env.Type = string(fd.Name())
+	} else if env.Type != string(fd.Name()) {
+		return nil, fmt.Errorf("payload %s does not match frame type %s", fd.Name(), env.Type)
+	}
+
+	payload, err := json.Marshal(messageToMap(m.Get(fd).Message()))
+	if err != nil {
+		return nil, err
+	}
+	env.Payload = payload
+	return env, nil
+}
+
+func payloadField(m protoreflect.Message, frameType string) protoreflect.FieldDescriptor {
+	return m.Descriptor().Oneofs().ByName("payload").Fields().ByName(protoreflect.Name(frameType))
+}
+
+// messageToMap 按 proto 字段名转成 JSON 对象。不用 protojson 是因为它把 int64 编码为字符串，
+// 而 JSON 客户端发送的是数字
+func messageToMap(m protoreflect.Message) map[string]interface{} {
+	out := make(map[string]interface{})
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsList() {
+			list := v.List()
+			items := make([]interface{}, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				items = append(items, fieldValue(fd, list.Get(i)))
+			}
+			out[string(fd.Name())] = items
+			return true
+		}
+		out[string(fd.Name())] = fieldValue(fd, v)
+		return true
+	})
+	return out
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	if fd.Kind() == protoreflect.MessageKind {
+		return messageToMap(v.Message())
+	}
+	return v.Interface()
+}
@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Version 当前的 WebSocket 信封版本。不带 v 的帧按旧格式处理
+const Version = 1
+
+// 帧类型。ack 在客户端发出时表示确认收到推送，在服务端发出时表示请求处理成功
+const (
+	FrameChat            = "chat"
+	FrameGroupMessage    = "group_message"
+	FrameSync            = "sync"
+	FramePing            = "ping"
+	FramePong            = "pong"
+	FrameAck             = "ack"
+	FrameError           = "error"
+	FrameMessageRecalled = "message_recalled"
+	FrameGroupCreated    = "group_created"
+	FrameTyping          = "typing"
+)
+
+// error 帧的错误码
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeNotFound           = "not_found"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeInternal           = "internal_error"
+)
+
+// Envelope 带版本的帧，payload 的结构由 type 决定。req_id 由客户端生成，服务端在 ack/error 中原样返回；
+// 服务端推送带 delivery_id，客户端需回复 ack
+type Envelope struct {
+	V          int             `json:"v"`
+	Type       string          `json:"type"`
+	ReqID      string          `json:"req_id,omitempty"`
+	DeliveryID string          `json:"delivery_id,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewEnvelope 构造服务端发出的帧，payload 为 nil 时不带 payload
+func NewEnvelope(frameType string, payload interface{}) Envelope {
+	env := Envelope{V: Version, Type: frameType}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("Failed to encode %s payload: %v", frameType, err)
+		}
+		env.Payload = data
+	}
+	return env
+}
+
+// ChatPayload 单聊消息，To 为接收者用户名
+type ChatPayload struct {
+	To      string `json:"to"`
+	Content string `json:"content"`
+}
+
+// GroupMessagePayload 群消息，To 为群组名称
+type GroupMessagePayload struct {
+	To      string `json:"to"`
+	Content string `json:"content"`
+}
+
+// AckPayload 客户端确认收到某次推送
+type AckPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// TypingPayload 客户端通知会话中的其他成员自己正在输入
+type TypingPayload struct {
+	ConversationID string `json:"conversation_id"`
+}
+
+// SyncRequest 客户端重连后请求 after_seq 之后的消息
+type SyncRequest struct {
+	ConversationID string `json:"conversation_id"`
+	AfterSeq       int64  `json:"after_seq"`
+	Limit          int    `json:"limit"`
+}
+
+// AckReply 消息已保存，客户端据此把消息从发送中标记为已发送
+type AckReply struct {
+	MessageID      string `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	Seq            int64  `json:"seq"`
+}
+
+type ErrorReply struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// ChatMessage 推送的单聊消息
+type ChatMessage struct {
+	To             string `json:"to,omitempty"`
+	From           string `json:"from,omitempty"`
+	FromUsername   string `json:"from_username,omitempty"`
+	SenderType     string `json:"sender_type,omitempty"`
+	Content        string `json:"content,omitempty"`
+	Timestamp      int64  `json:"timestamp,omitempty"`
+	MessageID      string `json:"message_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	Seq            int64  `json:"seq,omitempty"`
+}
+
+// GroupMessage 推送的群消息
+type GroupMessage struct {
+	ConversationID string `json:"conversation_id"`
+	GroupName      string `json:"group_name"`
+	From           string `json:"from"`
+	FromUsername   string `json:"from_username"`
+	Content        string `json:"content"`
+	MessageID      string `json:"message_id"`
+	Seq            int64  `json:"seq"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+type MessageRecalled struct {
+	MessageID string `json:"message_id"`
+	SenderID  string `json:"sender_id"`
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type GroupCreated struct {
+	ConversationID string `json:"conversation_id"`
+	GroupName      string `json:"group_name"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// Typing 临时事件，不需要确认
+type Typing struct {
+	ConversationID string `json:"conversation_id"`
+	UserID         string `json:"user_id"`
+}
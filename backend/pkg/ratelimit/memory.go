@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu     sync.Mutex
+	hits   map[string][]time.Time
+	blocks map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		hits:   make(map[string][]time.Time),
+		blocks: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.hits[key] = append(prune(s.hits[key], now.Add(-window)), now)
+
+	// 顺带清理长时间没有新事件的 key，避免内存无限增长
+	for k, hits := range s.hits {
+		if len(hits) > 0 && now.Sub(hits[len(hits)-1]) > window {
+			delete(s.hits, k)
+		}
+	}
+	return len(s.hits[key]), nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hits, key)
+	return nil
+}
+
+func (s *MemoryStore) Block(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, until := range s.blocks {
+		if now.After(until) {
+			delete(s.blocks, k)
+		}
+	}
+
+	if until := now.Add(ttl); until.After(s.blocks[key]) {
+		s.blocks[key] = until
+	}
+	return nil
+}
+
+func (s *MemoryStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if remaining := time.Until(s.blocks[key]); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// prune 去掉 since 之前的事件，hits 按时间升序排列
+func prune(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && hits[i].Before(since) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,54 @@
+// Package ratelimit 提供滑动窗口计数和临时封锁，用于登录限流等场景。
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Store 按 key 记录滑动窗口内的事件次数，并支持带有效期的封锁
+type Store interface {
+	// Hit 记录一次事件，返回窗口内（含本次）的事件总数
+	Hit(ctx context.Context, key string, window time.Duration) (int, error)
+	// Reset 清空 key 的计数
+	Reset(ctx context.Context, key string) error
+	// Block 封锁 key 一段时间，已有更长的封锁时保持不变
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	// BlockedFor 返回 key 剩余的封锁时间，未封锁时为 0
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+}
+
+var store Store = NewMemoryStore()
+
+// Init 配置了 Redis 时计数保存在 Redis 中，多实例共享；否则使用进程内存
+func Init(client *goredis.Client) {
+	if client == nil {
+		log.Println("Rate limit store: using in-memory counters")
+		store = NewMemoryStore()
+		return
+	}
+	log.Println("Rate limit store: using redis counters")
+	store = NewRedisStore(client)
+}
+
+func Hit(ctx context.Context, key string, window time.Duration) (int, error) {
+	return store.Hit(ctx, key, window)
+}
+
+func Reset(ctx context.Context, key string) error {
+	return store.Reset(ctx, key)
+}
+
+func Block(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return store.Block(ctx, key, ttl)
+}
+
+func BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	return store.BlockedFor(ctx, key)
+}
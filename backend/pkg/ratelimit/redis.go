@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	counterPrefix = "ratelimit:"
+	blockPrefix   = "ratelimit:block:"
+)
+
+// RedisStore 用有序集合实现滑动窗口：成员为每次事件，分数为事件发生的毫秒时间戳
+type RedisStore struct {
+	client *goredis.Client
+}
+
+func NewRedisStore(client *goredis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Hit(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now().UnixMilli()
+	start := now - window.Milliseconds()
+	member := fmt.Sprintf("%d-%s", now, uuid.New().String())
+
+	var card *goredis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, counterPrefix+key, "-inf", "("+strconv.FormatInt(start, 10))
+		pipe.ZAdd(ctx, counterPrefix+key, goredis.Z{Score: float64(now), Member: member})
+		card = pipe.ZCard(ctx, counterPrefix+key)
+		pipe.PExpire(ctx, counterPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(card.Val()), nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, counterPrefix+key).Err()
+}
+
+func (s *RedisStore) Block(ctx context.Context, key string, ttl time.Duration) error {
+	remaining, err := s.BlockedFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if remaining >= ttl {
+		return nil
+	}
+	return s.client.Set(ctx, blockPrefix+key, 1, ttl).Err()
+}
+
+func (s *RedisStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, blockPrefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 键不存在时 PTTL 返回负数
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
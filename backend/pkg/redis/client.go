@@ -28,6 +28,7 @@ func Init(config Config) error {
 
 	_, err := Client.Ping(context.Background()).Result()
 	if err != nil {
+		Client = nil
 		return fmt.Errorf("failed to connect redis: %w", err)
 	}
 
@@ -0,0 +1,56 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	keys     map[string]time.Time
+	handlers []func(Event)
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, expiresAt := range s.keys {
+		if now.After(expiresAt) {
+			delete(s.keys, k)
+		}
+	}
+	s.keys[key] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.keys[key]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryStore) Publish(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	handlers := append([]func(Event){}, s.handlers...)
+	s.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Subscribe(ctx context.Context, handler func(Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
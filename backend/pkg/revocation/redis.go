@@ -0,0 +1,63 @@
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix    = "revoked:"
+	eventChannel = "im:revocations"
+)
+
+type RedisStore struct {
+	client *goredis.Client
+}
+
+func NewRedisStore(client *goredis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, keyPrefix+key, 1, ttl).Err()
+}
+
+func (s *RedisStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, keyPrefix+key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *RedisStore) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, eventChannel, data).Err()
+}
+
+func (s *RedisStore) Subscribe(ctx context.Context, handler func(Event)) {
+	pubsub := s.client.Subscribe(ctx, eventChannel)
+
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Invalid revocation event: %v", err)
+				continue
+			}
+			handler(event)
+		}
+	}()
+}
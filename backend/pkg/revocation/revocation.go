@@ -0,0 +1,78 @@
+package revocation
+
+import (
+	"context"
+	"log"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Event 吊销事件，网关收到后断开对应会话（或该用户全部）的 WebSocket 连接
+type Event struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+}
+
+// Store 保存 denylist，并在服务之间广播吊销事件
+type Store interface {
+	Revoke(ctx context.Context, key string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, key string) (bool, error)
+	Publish(ctx context.Context, event Event) error
+	Subscribe(ctx context.Context, handler func(Event))
+}
+
+var store Store = NewMemoryStore()
+
+// Init 配置了 Redis 时使用 Redis 作为 denylist，否则使用进程内存（仅适合单实例部署）
+func Init(client *goredis.Client) {
+	if client == nil {
+		log.Println("Revocation store: using in-memory denylist")
+		store = NewMemoryStore()
+		return
+	}
+	log.Println("Revocation store: using redis denylist")
+	store = NewRedisStore(client)
+}
+
+// RevokeToken 把 access token 加入 denylist，直到其自然过期
+func RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return store.Revoke(ctx, "jti:"+tokenID, ttl)
+}
+
+func IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+	return store.IsRevoked(ctx, "jti:"+tokenID)
+}
+
+// RevokeSession 使携带该 sid 的 access token 全部失效，ttl 应不小于 access token 的有效期
+func RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if sessionID == "" {
+		return nil
+	}
+	return store.Revoke(ctx, "sid:"+sessionID, ttl)
+}
+
+func IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	return store.IsRevoked(ctx, "sid:"+sessionID)
+}
+
+// Publish 通知所有网关断开相关的 WebSocket 连接
+func Publish(ctx context.Context, event Event) error {
+	return store.Publish(ctx, event)
+}
+
+// Subscribe 在后台接收吊销事件，直到 ctx 结束
+func Subscribe(ctx context.Context, handler func(Event)) {
+	store.Subscribe(ctx, handler)
+}
@@ -0,0 +1,145 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// testStore 一个吊销存储以及让它的时间前进的方法
+type testStore struct {
+	Store
+	advance func(d time.Duration)
+}
+
+func testStores(t *testing.T) map[string]func(t *testing.T) testStore {
+	return map[string]func(t *testing.T) testStore{
+		"memory": func(t *testing.T) testStore {
+			return testStore{Store: NewMemoryStore(), advance: time.Sleep}
+		},
+		"redis": func(t *testing.T) testStore {
+			server := miniredis.RunT(t)
+			client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return testStore{Store: NewRedisStore(client), advance: server.FastForward}
+		},
+	}
+}
+
+// useStore 替换包级存储，测试结束后恢复
+func useStore(t *testing.T, s Store) {
+	t.Helper()
+	old := store
+	store = s
+	t.Cleanup(func() { store = old })
+}
+
+func TestStoreRevokeExpires(t *testing.T) {
+	ctx := context.Background()
+	for name, newStore := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+
+			if revoked, err := s.IsRevoked(ctx, "jti:a"); err != nil || revoked {
+				t.Fatalf("IsRevoked() before revoke = %v, %v", revoked, err)
+			}
+			if err := s.Revoke(ctx, "jti:a", 50*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := s.IsRevoked(ctx, "jti:a"); err != nil || !revoked {
+				t.Fatalf("IsRevoked() after revoke = %v, %v", revoked, err)
+			}
+			if revoked, _ := s.IsRevoked(ctx, "jti:b"); revoked {
+				t.Fatal("unrelated key revoked")
+			}
+
+			// 过期后自动移出 denylist，不会无限增长
+			s.advance(60 * time.Millisecond)
+			if revoked, err := s.IsRevoked(ctx, "jti:a"); err != nil || revoked {
+				t.Fatalf("IsRevoked() after ttl = %v, %v", revoked, err)
+			}
+		})
+	}
+}
+
+func TestRevokeTokenAndSession(t *testing.T) {
+	ctx := context.Background()
+	for name, newStore := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			useStore(t, newStore(t))
+
+			if err := RevokeToken(ctx, "token-1", time.Now().Add(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := IsTokenRevoked(ctx, "token-1"); err != nil || !revoked {
+				t.Fatalf("IsTokenRevoked() = %v, %v", revoked, err)
+			}
+			// jti 和 sid 使用不同的前缀，同名的会话不受影响
+			if revoked, _ := IsSessionRevoked(ctx, "token-1"); revoked {
+				t.Fatal("session with the token id revoked")
+			}
+
+			if err := RevokeSession(ctx, "session-1", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := IsSessionRevoked(ctx, "session-1"); err != nil || !revoked {
+				t.Fatalf("IsSessionRevoked() = %v, %v", revoked, err)
+			}
+
+			// 已经过期的 token 和空 ID 不需要进入 denylist
+			if err := RevokeToken(ctx, "token-2", time.Now().Add(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, _ := IsTokenRevoked(ctx, "token-2"); revoked {
+				t.Fatal("expired token added to the denylist")
+			}
+			if err := RevokeToken(ctx, "", time.Now().Add(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if err := RevokeSession(ctx, "", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, _ := IsTokenRevoked(ctx, ""); revoked {
+				t.Fatal("empty token id reported as revoked")
+			}
+			if revoked, _ := IsSessionRevoked(ctx, ""); revoked {
+				t.Fatal("empty session id reported as revoked")
+			}
+		})
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	for name, newStore := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			useStore(t, newStore(t))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := make(chan Event, 16)
+			Subscribe(ctx, func(event Event) { events <- event })
+
+			want := Event{UserID: "user-1", SessionID: "session-1", TokenID: "token-1"}
+			// Redis 的订阅异步生效，收到之前重复发布
+			deadline := time.After(5 * time.Second)
+			for {
+				if err := Publish(ctx, want); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case got := <-events:
+					if got != want {
+						t.Fatalf("event = %+v, want %+v", got, want)
+					}
+					return
+				case <-time.After(20 * time.Millisecond):
+				case <-deadline:
+					t.Fatal("event not delivered")
+				}
+			}
+		})
+	}
+}
@@ -2,83 +2,347 @@ package websocket
 
 import (
 	"log"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/cyperlo/im/pkg/protocol"
+	"github.com/google/uuid"
 	ws "github.com/gorilla/websocket"
 )
 
+// 自定义 WebSocket 关闭码（4000-4999 为应用保留）
+const (
+	CloseTokenRevoked = 4001
+	// CloseResync 连接跟不上推送速度被断开，客户端应重连并通过 sync 补齐消息
+	CloseResync = 4002
+)
+
 type Client struct {
-	ID     string
-	UserID string
-	Conn   *ws.Conn
-	Send   chan []byte
+	ID        string
+	UserID    string
+	SessionID string
+	Conn      *ws.Conn
+
+	// Codec 握手时协商出的编解码器，nil 表示旧格式。发送队列中的数据已按它编码
+	Codec protocol.Codec
+
+	queue    *sendQueue
+	slowOnce sync.Once
+
+	// 最近一次收到对端数据（pong 或消息）的时间，UnixNano
+	lastSeen atomic.Int64
+
+	// 已发送但客户端尚未确认的帧，按投递 ID 索引
+	pendingMu sync.Mutex
+	pending   map[string]*pendingFrame
 }
 
+// Hub 按用户分片保存连接，同一用户可以在多个设备上同时在线。
+// 配置了 Broker 和 Registry 时，发送的消息会转发给持有该用户连接的其他网关节点
 type Hub struct {
-	Clients map[string]*Client
-	mu      sync.RWMutex
+	shards [hubShardCount]hubShard
+	total  atomic.Int64
+
+	// mu 保护下面的集群配置
+	mu          sync.RWMutex
+	nodeID      string
+	broker      Broker
+	registry    Registry
+	registryTTL time.Duration
+	offline     OfflineQueue
 }
 
-var GlobalHub = &Hub{
-	Clients: make(map[string]*Client),
+func NewClient(id, userID, sessionID string, conn *ws.Conn, codec protocol.Codec) *Client {
+	return &Client{
+		ID:        id,
+		UserID:    userID,
+		SessionID: sessionID,
+		Conn:      conn,
+		Codec:     codec,
+		queue:     newSendQueue(),
+	}
 }
 
-func (h *Hub) RegisterClient(userID string, client *Client) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.Clients[userID] = client
-	log.Printf("Client registered: userID=%s, total clients=%d", userID, len(h.Clients))
+func NewHub() *Hub {
+	return &Hub{}
 }
 
-func (h *Hub) UnregisterClient(userID string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	delete(h.Clients, userID)
-	log.Printf("Client unregistered: userID=%s, total clients=%d", userID, len(h.Clients))
+var GlobalHub = NewHub()
+
+func (h *Hub) RegisterClient(client *Client) {
+	client.touch()
+	h.addClient(client)
+	h.registerConn(client)
+	h.drainOffline(client)
 }
 
-func SendToUser(userID string, message []byte) {
-	GlobalHub.mu.RLock()
-	defer GlobalHub.mu.RUnlock()
+func (h *Hub) addClient(client *Client) {
+	conns := h.shard(client.UserID).add(client)
+	total := h.total.Add(1)
+	log.Printf("Client registered: userID=%s, connID=%s, user connections=%d, total clients=%d",
+		client.UserID, client.ID, conns, total)
+}
 
-	log.Printf("SendToUser called: userID=%s, registered clients=%d", userID, len(GlobalHub.Clients))
+// UnregisterClient 只移除这一个连接，同一用户的其他连接不受影响。重复调用是安全的。
+// 连接上仍未确认的帧转入该用户的离线队列
+func (h *Hub) UnregisterClient(client *Client) {
+	if h.removeClient(client) {
+		h.unregisterConn(client)
+		h.enqueueOffline(client.UserID, client.takePending())
+	}
+}
 
-	if client, ok := GlobalHub.Clients[userID]; ok {
-		log.Printf("Client found for user %s, sending message", userID)
-		select {
-		case client.Send <- message:
-			log.Printf("Message sent to user %s", userID)
-		default:
-			log.Printf("Failed to send to user %s: channel full", userID)
+func (h *Hub) removeClient(client *Client) bool {
+	if !h.shard(client.UserID).remove(client) {
+		return false
+	}
+	client.queue.close()
+	total := h.total.Add(-1)
+	log.Printf("Client unregistered: userID=%s, connID=%s, total clients=%d", client.UserID, client.ID, total)
+	return true
+}
+
+// SendToClient 按连接的编解码器编码后只发送给这一个连接，不需要确认（如 pong）。
+// 连接已注销、编码失败或发送队列已满时返回 false
+func (h *Hub) SendToClient(client *Client, env protocol.Envelope) bool {
+	data, err := client.codec().Encode(&env)
+	if err != nil {
+		log.Printf("Failed to encode %s frame for conn %s: %v", env.Type, client.ID, err)
+		return false
+	}
+	return h.SendRawToClient(client, data)
+}
+
+// SendRawToClient 发送已经编码好的数据，用于回复旧格式的请求
+func (h *Hub) SendRawToClient(client *Client, message []byte) bool {
+	return client.enqueue(queuedFrame{data: message})
+}
+
+// UserClients 返回该用户在本节点上的全部连接
+func (h *Hub) UserClients(userID string) []*Client {
+	return slices.Clone(h.shard(userID).clients(userID))
+}
+
+// IsOnline 判断该用户在本节点上是否至少有一个存活的连接
+func (h *Hub) IsOnline(userID string) bool {
+	return slices.ContainsFunc(h.shard(userID).clients(userID), (*Client).Alive)
+}
+
+// DisconnectUser 发送关闭帧并断开该用户的全部连接，ReadPump 退出时会自动注销
+func (h *Hub) DisconnectUser(userID string, code int, reason string) {
+	clients := h.UserClients(userID)
+	if len(clients) == 0 {
+		return
+	}
+
+	log.Printf("Disconnecting user %s (%d connections): %s", userID, len(clients), reason)
+	for _, client := range clients {
+		client.Close(code, reason)
+	}
+}
+
+// DisconnectSession 只断开属于该会话的连接
+func (h *Hub) DisconnectSession(sessionID string, code int, reason string) {
+	var targets []*Client
+	h.eachClient(func(client *Client) bool {
+		if client.SessionID == sessionID {
+			targets = append(targets, client)
 		}
-	} else {
-		log.Printf("Client not found for user %s", userID)
+		return true
+	})
+
+	for _, client := range targets {
+		log.Printf("Disconnecting session %s of user %s: %s", sessionID, client.UserID, reason)
+		client.Close(code, reason)
 	}
 }
 
-func (c *Client) WritePump() {
-	defer c.Conn.Close()
+// SessionIDs 返回本节点上有连接的会话
+func (h *Hub) SessionIDs() []string {
+	seen := make(map[string]bool)
+	var sessions []string
+	h.eachClient(func(client *Client) bool {
+		if client.SessionID != "" && !seen[client.SessionID] {
+			seen[client.SessionID] = true
+			sessions = append(sessions, client.SessionID)
+		}
+		return true
+	})
+	return sessions
+}
 
-	for message := range c.Send {
-		if err := c.Conn.WriteMessage(1, message); err != nil {
-			log.Printf("Write error: %v", err)
-			break
+// Close 发送关闭帧后关闭底层连接，可在任意 goroutine 中调用
+func (c *Client) Close(code int, reason string) {
+	c.Conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.Conn.Close()
+}
+
+// SendToUser 把信封推送到该用户在所有节点上的每一个连接
+func SendToUser(userID string, env protocol.Envelope) {
+	GlobalHub.SendToUsers([]string{userID}, env)
+}
+
+// SendToUsers 把同一个信封推送给多个用户，如群消息
+func SendToUsers(userIDs []string, env protocol.Envelope) {
+	GlobalHub.SendToUsers(userIDs, env)
+}
+
+func (h *Hub) SendToUser(userID string, env protocol.Envelope) {
+	h.SendToUsers([]string{userID}, env)
+}
+
+// SendToUsers 为信封分配投递 ID，推送给本节点上这些用户的连接，并按节点批量转发给持有其连接的其他节点。
+// 同一次推送对每种编解码器只编码一次。每个连接都需要确认，未确认的帧会重投；用户不在线时放入离线队列
+func (h *Hub) SendToUsers(userIDs []string, env protocol.Envelope) {
+	if len(userIDs) == 0 {
+		return
+	}
+	env.DeliveryID = uuid.New().String()
+	h.fanOut(userIDs, newOutbound(env))
+}
+
+// SendEphemeral 推送不需要确认的临时事件（如输入状态），不重投也不进离线队列，发送队列已满时可能被丢弃。
+// key 非空时，coalesce 策略下连接队列中 key 相同、尚未写出的旧事件会被替换
+func (h *Hub) SendEphemeral(userIDs []string, env protocol.Envelope, key string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	env.DeliveryID = ""
+
+	out := newOutbound(env)
+	out.key = key
+	h.fanOut(userIDs, out)
+}
+
+func (h *Hub) fanOut(userIDs []string, out *outbound) {
+	local, conns := h.deliverLocal(userIDs, out)
+	if conns > 0 && out.reliable() {
+		log.Printf("Frame %s (%s) sent to %d connections of %d users", out.env.DeliveryID, out.env.Type, conns, len(userIDs))
+	}
+	h.forward(userIDs, out, local)
+}
+
+// deliverLocal 只推送给本节点上这些用户的连接，读取分片快照，不加锁。
+// 返回每个用户推送成功的连接数以及连接总数
+func (h *Hub) deliverLocal(userIDs []string, out *outbound) ([]int, int) {
+	local := make([]int, len(userIDs))
+	total := 0
+	for i, userID := range userIDs {
+		for _, client := range h.shard(userID).clients(userID) {
+			var ok bool
+			if out.reliable() {
+				ok = client.sendReliable(out)
+			} else {
+				ok = client.sendEphemeral(out)
+			}
+			if ok {
+				local[i]++
+			}
+		}
+		total += local[i]
+	}
+	return local, total
+}
+
+func (c *Client) sendEphemeral(out *outbound) bool {
+	data, err := out.encode(c.codec())
+	if err != nil {
+		log.Printf("Failed to encode %s frame for conn %s: %v", out.env.Type, c.ID, err)
+		return false
+	}
+	return c.enqueue(queuedFrame{data: data, key: out.key})
+}
+
+func (c *Client) codec() protocol.Codec {
+	if c.Codec == nil {
+		return protocol.Legacy
+	}
+	return c.Codec
+}
+
+// messageType 二进制编解码器使用 binary 帧，其余使用 text 帧
+func (c *Client) messageType() int {
+	if c.codec().Binary() {
+		return ws.BinaryMessage
+	}
+	return ws.TextMessage
+}
+
+// Alive 判断在 PongWait 内是否收到过对端的数据。超时的连接会因读超时被 ReadPump 关闭，
+// 这里用于覆盖从超时到注销之间的窗口
+func (c *Client) Alive() bool {
+	return time.Since(time.Unix(0, c.lastSeen.Load())) < config.PongWait
+}
+
+func (c *Client) touch() {
+	c.lastSeen.Store(time.Now().UnixNano())
+}
+
+// WritePump 负责该连接的全部数据帧写入，定时发送 ping 并重投未确认的帧。每次写入都设置超时，
+// 对端卡住时写入失败并关闭连接
+func (c *Client) WritePump(h *Hub) {
+	ticker := time.NewTicker(config.PingInterval)
+	retryTicker := time.NewTicker(redeliverInterval)
+	defer func() {
+		ticker.Stop()
+		retryTicker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.queue.notify:
+			frames, closed := c.queue.take()
+			for _, frame := range frames {
+				if err := c.write(frame.data); err != nil {
+					log.Printf("Write error: %v", err)
+					return
+				}
+			}
+			if closed {
+				// 连接已从 hub 注销
+				c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+				c.Conn.WriteMessage(ws.CloseMessage, []byte{})
+				return
+			}
+		case <-retryTicker.C:
+			if err := c.redeliver(h); err != nil {
+				log.Printf("Redeliver error: %v", err)
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := c.Conn.WriteMessage(ws.PingMessage, nil); err != nil {
+				log.Printf("Ping error: %v", err)
+				return
+			}
 		}
 	}
 }
 
-func (c *Client) ReadPump(h *Hub, userID string, onMessage func([]byte)) {
+// ReadPump 读取客户端消息。收到 pong 或任意消息都会延长读超时，PongWait 内没有任何数据则断开
+func (c *Client) ReadPump(h *Hub, onMessage func([]byte)) {
 	defer func() {
-		h.UnregisterClient(userID)
+		h.UnregisterClient(c)
 		c.Conn.Close()
 	}()
 
+	c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		c.touch()
+		return c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	})
+
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			log.Printf("Read error: %v", err)
 			break
 		}
+		c.touch()
+		c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
 		if onMessage != nil {
 			onMessage(message)
 		}
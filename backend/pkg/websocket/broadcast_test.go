@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+	"github.com/cyperlo/im/pkg/protocol/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// quietLog 注册和推送的日志会淹没测试输出
+func quietLog(tb testing.TB) {
+	tb.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+}
+
+func withConfig(tb testing.TB, c Config) {
+	tb.Helper()
+	old := config
+	if err := Init(c); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { config = old })
+}
+
+var testCodecs = []protocol.Codec{protocol.JSON, protocol.Proto, nil}
+
+// newTestHub 为每个用户注册 conns 个连接，编解码器在 JSON、protobuf 和旧格式之间轮换
+func newTestHub(members, conns int) (*Hub, []string, []*Client) {
+	hub := NewHub()
+	userIDs := make([]string, members)
+	clients := make([]*Client, 0, members*conns)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprintf("user-%d", i)
+		for c := 0; c < conns; c++ {
+			client := NewClient(fmt.Sprintf("conn-%d-%d", i, c), userIDs[i], "", nil, testCodecs[len(clients)%len(testCodecs)])
+			hub.RegisterClient(client)
+			clients = append(clients, client)
+		}
+	}
+	return hub, userIDs, clients
+}
+
+func testGroupMessage() protocol.Envelope {
+	return protocol.NewEnvelope(protocol.FrameGroupMessage, protocol.GroupMessage{
+		ConversationID: "group-1",
+		GroupName:      "bench",
+		From:           "sender",
+		FromUsername:   "sender",
+		Content:        "hello, everyone",
+		MessageID:      "message-1",
+		Seq:            1,
+		Timestamp:      time.Now().Unix(),
+	})
+}
+
+// drainClients 模拟 WritePump 写出并收到确认，避免发送队列和待确认帧在多轮之间堆积
+func drainClients(clients []*Client) int {
+	frames := 0
+	for _, client := range clients {
+		taken, _ := client.queue.take()
+		frames += len(taken)
+		client.takePending()
+	}
+	return frames
+}
+
+func TestSendToUsersDeliversToEveryConnection(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hub, userIDs, clients := newTestHub(30, 2)
+	hub.SendToUsers(userIDs, testGroupMessage())
+
+	var deliveryID string
+	for _, client := range clients {
+		frames, _ := client.queue.take()
+		if len(frames) != 1 {
+			t.Fatalf("conn %s got %d frames, want 1", client.ID, len(frames))
+		}
+		if deliveryID == "" {
+			deliveryID = frames[0].deliveryID
+		}
+		if frames[0].deliveryID != deliveryID {
+			t.Errorf("conn %s got delivery %s, want %s", client.ID, frames[0].deliveryID, deliveryID)
+		}
+
+		frameType, frameDeliveryID, err := frameHeader(client.codec(), frames[0].data)
+		if err != nil {
+			t.Fatalf("conn %s: %v", client.ID, err)
+		}
+		if frameType != protocol.FrameGroupMessage || frameDeliveryID != deliveryID {
+			t.Errorf("conn %s decoded type %q delivery %q", client.ID, frameType, frameDeliveryID)
+		}
+		if !client.Ack(deliveryID) {
+			t.Errorf("conn %s has no pending frame %s", client.ID, deliveryID)
+		}
+	}
+}
+
+func TestSendToUsersEncodesOncePerCodec(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	_, _, clients := newTestHub(9, 1)
+	out := newOutbound(testGroupMessage())
+	out.env.DeliveryID = "delivery-1"
+	for _, client := range clients {
+		if !client.sendReliable(out) {
+			t.Fatalf("sendReliable to %s failed", client.ID)
+		}
+	}
+	if len(out.encoded) != len(testCodecs) {
+		t.Fatalf("encoded %d times, want once per codec (%d)", len(out.encoded), len(testCodecs))
+	}
+
+	// 同一种编解码器的连接共享同一份编码结果
+	seen := make(map[string]*byte)
+	for _, client := range clients {
+		frames, _ := client.queue.take()
+		name := client.codec().Name()
+		if first, ok := seen[name]; ok && first != &frames[0].data[0] {
+			t.Errorf("conn %s got its own copy of the %s encoding", client.ID, name)
+		}
+		seen[name] = &frames[0].data[0]
+	}
+}
+
+func TestSendEphemeralIsNotPending(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hub, userIDs, clients := newTestHub(3, 1)
+	hub.SendEphemeral(userIDs, protocol.NewEnvelope(protocol.FrameTyping, protocol.Typing{ConversationID: "c", UserID: "u"}), "typing:c:u")
+	for _, client := range clients {
+		frames, _ := client.queue.take()
+		if len(frames) != 1 || frames[0].reliable() || frames[0].key != "typing:c:u" {
+			t.Fatalf("conn %s got %+v", client.ID, frames)
+		}
+		if pending := client.takePending(); len(pending) != 0 {
+			t.Errorf("conn %s has %d pending ephemeral frames", client.ID, len(pending))
+		}
+	}
+}
+
+// frameHeader 解出推送帧的类型和投递 ID
+func frameHeader(codec protocol.Codec, data []byte) (string, string, error) {
+	if codec.Binary() {
+		var frame pb.ServerFrame
+		if err := proto.Unmarshal(data, &frame); err != nil {
+			return "", "", err
+		}
+		return frame.Type, frame.DeliveryId, nil
+	}
+
+	// JSON 信封和旧格式的推送都在顶层带 type 和 delivery_id
+	var header struct {
+		Type       string `json:"type"`
+		DeliveryID string `json:"delivery_id"`
+	}
+	err := json.Unmarshal(data, &header)
+	return header.Type, header.DeliveryID, err
+}
+
+// BenchmarkSendToUsers 向一个大群扇出一条可靠消息，成员的连接混用 JSON、protobuf 和旧格式
+func BenchmarkSendToUsers(b *testing.B) {
+	for _, members := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("members=%d", members), func(b *testing.B) {
+			benchmarkFanOut(b, members, func(hub *Hub, userIDs []string, env protocol.Envelope) {
+				hub.SendToUsers(userIDs, env)
+			})
+		})
+	}
+}
+
+// BenchmarkSendToUserPerMember 逐个成员调用 SendToUser，作为 SendToUsers 的对照
+func BenchmarkSendToUserPerMember(b *testing.B) {
+	for _, members := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("members=%d", members), func(b *testing.B) {
+			benchmarkFanOut(b, members, func(hub *Hub, userIDs []string, env protocol.Envelope) {
+				for _, userID := range userIDs {
+					hub.SendToUser(userID, env)
+				}
+			})
+		})
+	}
+}
+
+func benchmarkFanOut(b *testing.B, members int, send func(hub *Hub, userIDs []string, env protocol.Envelope)) {
+	quietLog(b)
+	withConfig(b, Config{})
+
+	hub, userIDs, clients := newTestHub(members, 1)
+	env := testGroupMessage()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		send(hub, userIDs, env)
+
+		b.StopTimer()
+		if frames := drainClients(clients); frames != len(clients) {
+			b.Fatalf("delivered %d frames, want %d", frames, len(clients))
+		}
+		b.StartTimer()
+	}
+}
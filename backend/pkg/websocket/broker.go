@@ -0,0 +1,25 @@
+package websocket
+
+import (
+	"context"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+// Delivery 在网关节点之间转发的一次投递，Origin 为发出投递的节点。
+// 转发的是未编码的信封，由接收节点按各连接的编解码器编码；信封不带投递 ID 的是临时事件，Key 用于合并。
+// Broadcast 表示查询注册表失败后发给了所有节点，接收节点不持有某个用户的连接是正常的
+type Delivery struct {
+	Origin    string            `json:"origin"`
+	UserIDs   []string          `json:"user_ids"`
+	Envelope  protocol.Envelope `json:"envelope"`
+	Key       string            `json:"key,omitempty"`
+	Broadcast bool              `json:"broadcast,omitempty"`
+}
+
+// Broker 在节点之间转发投递。Publish 的 nodeID 为空时发给所有节点，否则只发给该节点；
+// Subscribe 同时接收广播和发给本节点的投递
+type Broker interface {
+	Publish(ctx context.Context, nodeID string, delivery Delivery) error
+	Subscribe(ctx context.Context, nodeID string, handler func(Delivery))
+}
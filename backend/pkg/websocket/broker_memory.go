@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker 进程内的 Broker，多个 Hub 共用一个实例即可模拟多个节点
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers []memorySubscriber
+}
+
+type memorySubscriber struct {
+	nodeID  string
+	handler func(Delivery)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, nodeID string, delivery Delivery) error {
+	b.mu.Lock()
+	subscribers := append([]memorySubscriber{}, b.subscribers...)
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if nodeID == "" || sub.nodeID == nodeID {
+			sub.handler(delivery)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, nodeID string, handler func(Delivery)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, memorySubscriber{nodeID: nodeID, handler: handler})
+}
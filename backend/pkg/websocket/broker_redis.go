@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 广播频道以及每个节点自己的频道 im:deliveries:{nodeID}
+const deliveryChannel = "im:deliveries"
+
+type RedisBroker struct {
+	client *goredis.Client
+}
+
+func NewRedisBroker(client *goredis.Client) *RedisBroker {
+	return &RedisBroker{client: client}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, nodeID string, delivery Delivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, nodeChannel(nodeID), data).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, nodeID string, handler func(Delivery)) {
+	pubsub := b.client.Subscribe(ctx, deliveryChannel, nodeChannel(nodeID))
+
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var delivery Delivery
+			if err := json.Unmarshal([]byte(msg.Payload), &delivery); err != nil {
+				log.Printf("Invalid delivery: %v", err)
+				continue
+			}
+			handler(delivery)
+		}
+	}()
+}
+
+func nodeChannel(nodeID string) string {
+	if nodeID == "" {
+		return deliveryChannel
+	}
+	return deliveryChannel + ":" + nodeID
+}
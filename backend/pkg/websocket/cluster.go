@@ -0,0 +1,282 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const clusterOpTimeout = 3 * time.Second
+
+// InitCluster 配置了 Redis 时通过 Redis 转发投递并保存连接注册表，否则只在进程内投递（仅适合单实例部署）。
+// nodeID 为空时根据主机名生成
+func InitCluster(client *goredis.Client, nodeID string, registryTTL time.Duration) {
+	if nodeID == "" {
+		nodeID = defaultNodeID()
+	}
+
+	if client == nil {
+		log.Printf("WebSocket cluster: using in-memory broker and registry (node %s)", nodeID)
+		GlobalHub.UseCluster(context.Background(), nodeID, NewMemoryBroker(), NewMemoryRegistry(), registryTTL)
+		GlobalHub.UseOfflineQueue(NewMemoryOfflineQueue())
+		return
+	}
+	log.Printf("WebSocket cluster: using redis broker and registry (node %s)", nodeID)
+	GlobalHub.UseCluster(context.Background(), nodeID, NewRedisBroker(client), NewRedisRegistry(client), registryTTL)
+	GlobalHub.UseOfflineQueue(NewRedisOfflineQueue(client))
+}
+
+func defaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return host + "-" + uuid.New().String()[:8]
+}
+
+// UseCluster 设置跨节点转发和连接注册表，开始接收发往本节点的投递，并定期为存活的连接续期，直到 ctx 结束
+func (h *Hub) UseCluster(ctx context.Context, nodeID string, broker Broker, registry Registry, registryTTL time.Duration) {
+	if registryTTL <= 0 {
+		registryTTL = 2 * config.PongWait
+	}
+
+	h.mu.Lock()
+	h.nodeID = nodeID
+	h.broker = broker
+	h.registry = registry
+	h.registryTTL = registryTTL
+	h.mu.Unlock()
+
+	broker.Subscribe(ctx, nodeID, func(delivery Delivery) {
+		// 本节点发出的投递已经在本地推送过
+		if delivery.Origin == nodeID {
+			return
+		}
+		out := newOutbound(delivery.Envelope)
+		out.key = delivery.Key
+		local, _ := h.deliverLocal(delivery.UserIDs, out)
+		if !out.reliable() || delivery.Broadcast {
+			return
+		}
+
+		// 注册表指向本节点，但用户在查询之后已经断开，转入离线队列而不是丢弃
+		var offline []string
+		for i, userID := range delivery.UserIDs {
+			if local[i] == 0 {
+				offline = append(offline, userID)
+			}
+		}
+		h.enqueueOfflineMany(offline, out.env)
+	})
+
+	go h.refreshLoop(ctx)
+}
+
+// UseOfflineQueue 设置离线队列，未设置时离线和未确认的帧会被丢弃
+func (h *Hub) UseOfflineQueue(queue OfflineQueue) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.offline = queue
+}
+
+func (h *Hub) NodeID() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.nodeID
+}
+
+// Connections 返回这些用户在各节点上的连接，未配置注册表时只包含本节点
+func (h *Hub) Connections(ctx context.Context, userIDs ...string) (map[string]map[string][]string, error) {
+	h.mu.RLock()
+	registry := h.registry
+	h.mu.RUnlock()
+	if registry != nil {
+		return registry.Lookup(ctx, userIDs...)
+	}
+
+	result := make(map[string]map[string][]string, len(userIDs))
+	for _, userID := range userIDs {
+		nodes := make(map[string][]string)
+		for _, client := range h.UserClients(userID) {
+			nodes[h.NodeID()] = append(nodes[h.NodeID()], client.ID)
+		}
+		result[userID] = nodes
+	}
+	return result, nil
+}
+
+// ConnectedUsers 返回在任意节点上有连接的用户
+func (h *Hub) ConnectedUsers(ctx context.Context) ([]string, error) {
+	h.mu.RLock()
+	registry := h.registry
+	h.mu.RUnlock()
+	if registry != nil {
+		return registry.Users(ctx)
+	}
+
+	var users []string
+	for i := range h.shards {
+		for userID := range h.shards[i].snapshot() {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+// OnlineSessions 返回该用户在任意节点上有连接的会话；未配置注册表或查询失败时只包含本节点上存活的连接
+func (h *Hub) OnlineSessions(ctx context.Context, userID string) map[string]bool {
+	h.mu.RLock()
+	registry := h.registry
+	h.mu.RUnlock()
+
+	online := make(map[string]bool)
+	if registry != nil {
+		sessions, err := registry.Sessions(ctx, userID)
+		if err == nil {
+			for _, sessionID := range sessions {
+				online[sessionID] = true
+			}
+			return online
+		}
+		log.Printf("Failed to look up sessions of user %s, using local conns: %v", userID, err)
+	}
+
+	for _, client := range h.shard(userID).clients(userID) {
+		if client.SessionID != "" && client.Alive() {
+			online[client.SessionID] = true
+		}
+	}
+	return online
+}
+
+// forward 按注册表把这些用户分组到持有其连接的其他节点，每个节点只发布一次；查询注册表失败时退回广播给所有节点。
+// local 为各用户在本节点上推送的连接数，在任何节点上都没有连接的用户放入离线队列（临时事件除外）。
+// 广播时无法知道其他节点是否推送成功，本节点没有推送到的用户都放入离线队列，
+// 其他节点上已经收到的设备会再收到一次，由客户端按投递 ID 去重
+func (h *Hub) forward(userIDs []string, out *outbound, local []int) {
+	h.mu.RLock()
+	nodeID, broker, registry := h.nodeID, h.broker, h.registry
+	h.mu.RUnlock()
+	if broker == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+
+	targets := make(map[string][]string)
+	if registry != nil {
+		conns, err := registry.Lookup(ctx, userIDs...)
+		if err == nil {
+			var offline []string
+			for i, userID := range userIDs {
+				remote := 0
+				for node := range conns[userID] {
+					if node != nodeID {
+						targets[node] = append(targets[node], userID)
+						remote++
+					}
+				}
+				if local[i] == 0 && remote == 0 && out.reliable() {
+					offline = append(offline, userID)
+				}
+			}
+			// 大群中不在线的成员可能很多，一次写入离线队列
+			h.enqueueOfflineMany(offline, out.env)
+		} else {
+			log.Printf("Failed to look up connections of %d users, broadcasting: %v", len(userIDs), err)
+			targets[""] = userIDs
+		}
+	} else {
+		targets[""] = userIDs
+	}
+	if _, broadcast := targets[""]; broadcast && out.reliable() {
+		var offline []string
+		for i, userID := range userIDs {
+			if local[i] == 0 {
+				offline = append(offline, userID)
+			}
+		}
+		h.enqueueOfflineMany(offline, out.env)
+	}
+
+	for target, users := range targets {
+		delivery := Delivery{Origin: nodeID, UserIDs: users, Envelope: out.env, Key: out.key, Broadcast: target == ""}
+		if err := broker.Publish(ctx, target, delivery); err != nil {
+			log.Printf("Failed to publish %s delivery for %d users to node %q: %v", out.env.Type, len(users), target, err)
+		}
+	}
+}
+
+func (h *Hub) registerConn(client *Client) {
+	h.mu.RLock()
+	nodeID, registry, ttl := h.nodeID, h.registry, h.registryTTL
+	h.mu.RUnlock()
+	if registry == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := registry.Register(ctx, Conn{UserID: client.UserID, NodeID: nodeID, ConnID: client.ID, SessionID: client.SessionID}, ttl); err != nil {
+		log.Printf("Failed to register conn %s of user %s: %v", client.ID, client.UserID, err)
+	}
+}
+
+func (h *Hub) unregisterConn(client *Client) {
+	h.mu.RLock()
+	nodeID, registry := h.nodeID, h.registry
+	h.mu.RUnlock()
+	if registry == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := registry.Unregister(ctx, Conn{UserID: client.UserID, NodeID: nodeID, ConnID: client.ID, SessionID: client.SessionID}); err != nil {
+		log.Printf("Failed to unregister conn %s of user %s: %v", client.ID, client.UserID, err)
+	}
+}
+
+// refreshLoop 每隔 TTL 的三分之一为存活的连接续期，心跳超时的连接不再续期，随后自动过期
+func (h *Hub) refreshLoop(ctx context.Context) {
+	h.mu.RLock()
+	ttl := h.registryTTL
+	h.mu.RUnlock()
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.refreshConns(ttl)
+		}
+	}
+}
+
+func (h *Hub) refreshConns(ttl time.Duration) {
+	h.mu.RLock()
+	nodeID, registry := h.nodeID, h.registry
+	h.mu.RUnlock()
+
+	conns := make([]Conn, 0, h.total.Load())
+	h.eachClient(func(client *Client) bool {
+		if client.Alive() {
+			conns = append(conns, Conn{UserID: client.UserID, NodeID: nodeID, ConnID: client.ID, SessionID: client.SessionID})
+		}
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := registry.Refresh(ctx, conns, ttl); err != nil {
+		log.Printf("Failed to refresh connection registry: %v", err)
+	}
+}
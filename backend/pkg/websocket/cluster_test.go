@@ -0,0 +1,216 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+// recordingOfflineQueue 记录每次写入，用于检查离线帧是否批量写入
+type recordingOfflineQueue struct {
+	*MemoryOfflineQueue
+
+	mu       sync.Mutex
+	pushes   int
+	pushMany [][]string
+}
+
+func newRecordingOfflineQueue() *recordingOfflineQueue {
+	return &recordingOfflineQueue{MemoryOfflineQueue: NewMemoryOfflineQueue()}
+}
+
+func (q *recordingOfflineQueue) Push(ctx context.Context, userID string, frames []protocol.Envelope) error {
+	q.mu.Lock()
+	q.pushes++
+	q.mu.Unlock()
+	return q.MemoryOfflineQueue.Push(ctx, userID, frames)
+}
+
+func (q *recordingOfflineQueue) PushMany(ctx context.Context, userIDs []string, frame protocol.Envelope) error {
+	q.mu.Lock()
+	q.pushMany = append(q.pushMany, slices.Clone(userIDs))
+	q.mu.Unlock()
+	return q.MemoryOfflineQueue.PushMany(ctx, userIDs, frame)
+}
+
+// failingRegistry 查询总是失败，投递退回广播
+type failingRegistry struct {
+	*MemoryRegistry
+}
+
+func (failingRegistry) Lookup(ctx context.Context, userIDs ...string) (map[string]map[string][]string, error) {
+	return nil, errors.New("registry unavailable")
+}
+
+func (failingRegistry) Sessions(ctx context.Context, userID string) ([]string, error) {
+	return nil, errors.New("registry unavailable")
+}
+
+// newTestCluster 创建共用 broker、注册表和离线队列的多个节点，registry 为空时使用内存注册表
+func newTestCluster(t *testing.T, nodes int, registry Registry) ([]*Hub, *recordingOfflineQueue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	if registry == nil {
+		registry = NewMemoryRegistry()
+	}
+	broker, offline := NewMemoryBroker(), newRecordingOfflineQueue()
+	hubs := make([]*Hub, nodes)
+	for i := range hubs {
+		hubs[i] = NewHub()
+		hubs[i].UseCluster(ctx, string(rune('a'+i)), broker, registry, time.Minute)
+		hubs[i].UseOfflineQueue(offline)
+	}
+	return hubs, offline
+}
+
+func TestForwardBatchesOfflineUsers(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hubs, offline := newTestCluster(t, 2, nil)
+	onA := NewClient("conn-a", "alice", "", nil, protocol.JSON)
+	onB := NewClient("conn-b", "bob", "", nil, protocol.Proto)
+	hubs[0].RegisterClient(onA)
+	hubs[1].RegisterClient(onB)
+
+	members := []string{"alice", "bob", "carol", "dave", "erin"}
+	hubs[0].SendToUsers(members, testGroupMessage())
+
+	for _, client := range []*Client{onA, onB} {
+		if frames, _ := client.queue.take(); len(frames) != 1 {
+			t.Errorf("conn %s got %d frames, want 1", client.ID, len(frames))
+		}
+	}
+
+	if offline.pushes != 0 {
+		t.Errorf("Push called %d times, want offline users batched", offline.pushes)
+	}
+	if len(offline.pushMany) != 1 || !slices.Equal(offline.pushMany[0], []string{"carol", "dave", "erin"}) {
+		t.Fatalf("PushMany calls = %v, want one call for [carol dave erin]", offline.pushMany)
+	}
+	for _, userID := range []string{"carol", "dave", "erin"} {
+		if frames, _ := offline.Drain(context.Background(), userID); len(frames) != 1 {
+			t.Errorf("offline queue of %s has %d frames, want 1", userID, len(frames))
+		}
+	}
+}
+
+func TestForwardEphemeralSkipsOfflineQueue(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hubs, offline := newTestCluster(t, 1, nil)
+	hubs[0].SendEphemeral([]string{"carol"}, protocol.NewEnvelope(protocol.FrameTyping, protocol.Typing{ConversationID: "c", UserID: "u"}), "")
+
+	if offline.pushes != 0 || len(offline.pushMany) != 0 {
+		t.Fatalf("ephemeral frame queued offline: pushes=%d pushMany=%v", offline.pushes, offline.pushMany)
+	}
+}
+
+// 离线帧推送给用户在本节点上的全部连接，而不只是刚建立的那一个
+func TestDrainOfflineDeliversToEveryConnection(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hubs, offline := newTestCluster(t, 1, nil)
+	phone := NewClient("phone", "alice", "", nil, protocol.JSON)
+	hubs[0].RegisterClient(phone)
+
+	if err := offline.Push(context.Background(), "alice", []protocol.Envelope{testFrame("d1"), testFrame("d2")}); err != nil {
+		t.Fatal(err)
+	}
+	laptop := NewClient("laptop", "alice", "", nil, protocol.Proto)
+	hubs[0].RegisterClient(laptop)
+
+	for _, client := range []*Client{phone, laptop} {
+		frames, _ := client.queue.take()
+		var ids []string
+		for _, frame := range frames {
+			ids = append(ids, frame.deliveryID)
+		}
+		if !slices.Equal(ids, []string{"d1", "d2"}) {
+			t.Errorf("conn %s got %v, want [d1 d2]", client.ID, ids)
+		}
+		if !client.Ack("d1") || !client.Ack("d2") {
+			t.Errorf("conn %s is not waiting for acks of the offline frames", client.ID)
+		}
+	}
+	if frames, _ := offline.Drain(context.Background(), "alice"); len(frames) != 0 {
+		t.Errorf("offline queue still has %d frames", len(frames))
+	}
+}
+
+// 注册表指向的节点上用户已经断开时，帧转入离线队列而不是丢弃
+func TestDeliveryFallsBackToOfflineQueue(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hubs, offline := newTestCluster(t, 2, nil)
+	bob := NewClient("conn-b", "bob", "", nil, protocol.JSON)
+	hubs[1].RegisterClient(bob)
+	// 连接已从节点 b 移除，但注销注册表之前推送就已经查到了它
+	hubs[1].removeClient(bob)
+
+	hubs[0].SendToUsers([]string{"bob"}, testGroupMessage())
+
+	if len(offline.pushMany) != 1 || !slices.Equal(offline.pushMany[0], []string{"bob"}) {
+		t.Fatalf("PushMany calls = %v, want node b to queue bob", offline.pushMany)
+	}
+	frames, _ := offline.Drain(context.Background(), "bob")
+	if len(frames) != 1 || frames[0].Type != protocol.FrameGroupMessage {
+		t.Fatalf("offline queue of bob = %+v", frames)
+	}
+}
+
+// 广播投递到达不持有该用户连接的节点是正常的，不能由每个节点各自放入离线队列；
+// 发起的节点不知道谁收到了，本地没有推送到的用户由它统一放入离线队列一次
+func TestBroadcastDeliveryQueuesOfflineOnce(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hubs, offline := newTestCluster(t, 3, failingRegistry{NewMemoryRegistry()})
+	alice := NewClient("conn-a", "alice", "", nil, protocol.JSON)
+	bob := NewClient("conn-b", "bob", "", nil, protocol.JSON)
+	hubs[0].RegisterClient(alice)
+	hubs[1].RegisterClient(bob)
+
+	hubs[0].SendToUsers([]string{"alice", "bob", "carol"}, testGroupMessage())
+
+	if frames, _ := bob.queue.take(); len(frames) != 1 {
+		t.Fatalf("bob got %d frames over broadcast, want 1", len(frames))
+	}
+	if offline.pushes != 0 || len(offline.pushMany) != 1 || !slices.Equal(offline.pushMany[0], []string{"bob", "carol"}) {
+		t.Fatalf("offline writes: pushes=%d pushMany=%v, want one PushMany for [bob carol]", offline.pushes, offline.pushMany)
+	}
+}
+
+// 会话连接在其他节点上时同样算作在线；注册表不可用时只能看到本节点的连接
+func TestOnlineSessionsAcrossNodes(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	for name, registry := range map[string]Registry{"registry": nil, "registry unavailable": failingRegistry{NewMemoryRegistry()}} {
+		t.Run(name, func(t *testing.T) {
+			hubs, _ := newTestCluster(t, 2, registry)
+			hubs[0].RegisterClient(NewClient("phone", "alice", "s1", nil, protocol.JSON))
+			hubs[1].RegisterClient(NewClient("laptop", "alice", "s2", nil, protocol.JSON))
+			hubs[1].RegisterClient(NewClient("tablet", "bob", "s3", nil, protocol.JSON))
+
+			want := map[string]bool{"s1": true, "s2": true}
+			if registry != nil {
+				want = map[string]bool{"s1": true}
+			}
+			if got := hubs[0].OnlineSessions(context.Background(), "alice"); !maps.Equal(got, want) {
+				t.Errorf("OnlineSessions(alice) = %v, want %v", got, want)
+			}
+		})
+	}
+}
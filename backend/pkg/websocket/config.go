@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"errors"
+	"time"
+)
+
+// Config 连接保活参数：每 PingInterval 发送一次 ping，PongWait 内没有收到任何数据（pong 或消息）
+// 即认为连接已断开；单次写入超过 WriteWait 视为对端卡死。
+// 推送的帧在 AckTimeout 内未被确认则重投，最多重投 MaxRedeliveries 次后转入离线队列。
+// 每个连接最多缓存 SendBuffer 帧待写出，写满后按 SlowConsumerPolicy 处理；
+// 等待确认的帧超过 MaxPendingPerConn 时以 CloseResync 断开
+type Config struct {
+	PingInterval       time.Duration
+	PongWait           time.Duration
+	WriteWait          time.Duration
+	AckTimeout         time.Duration
+	MaxRedeliveries    int
+	SendBuffer         int
+	SlowConsumerPolicy SlowConsumerPolicy
+	MaxPendingPerConn  int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PingInterval:       25 * time.Second,
+		PongWait:           60 * time.Second,
+		WriteWait:          10 * time.Second,
+		AckTimeout:         5 * time.Second,
+		MaxRedeliveries:    5,
+		SendBuffer:         256,
+		SlowConsumerPolicy: PolicyCoalesce,
+		MaxPendingPerConn:  1024,
+	}
+}
+
+var config = DefaultConfig()
+
+// Init 设置保活参数，应在建立连接之前调用
+func Init(c Config) error {
+	defaults := DefaultConfig()
+	if c.PingInterval <= 0 {
+		c.PingInterval = defaults.PingInterval
+	}
+	if c.PongWait <= 0 {
+		c.PongWait = defaults.PongWait
+	}
+	if c.WriteWait <= 0 {
+		c.WriteWait = defaults.WriteWait
+	}
+	if c.AckTimeout <= 0 {
+		c.AckTimeout = defaults.AckTimeout
+	}
+	if c.MaxRedeliveries < 0 {
+		c.MaxRedeliveries = defaults.MaxRedeliveries
+	}
+	if c.SendBuffer <= 0 {
+		c.SendBuffer = defaults.SendBuffer
+	}
+	if c.MaxPendingPerConn <= 0 {
+		c.MaxPendingPerConn = defaults.MaxPendingPerConn
+	}
+	policy, err := parseSlowConsumerPolicy(c.SlowConsumerPolicy)
+	if err != nil {
+		return err
+	}
+	c.SlowConsumerPolicy = policy
+	if c.PingInterval >= c.PongWait {
+		return errors.New("websocket ping interval must be shorter than pong wait")
+	}
+	config = c
+	return nil
+}
@@ -0,0 +1,22 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+const (
+	offlineQueueLimit = 1000
+	offlineQueueTTL   = 7 * 24 * time.Hour
+)
+
+// OfflineQueue 保存用户离线期间以及连接关闭时仍未确认的帧。每个用户最多保留最近 offlineQueueLimit 条
+type OfflineQueue interface {
+	Push(ctx context.Context, userID string, frames []protocol.Envelope) error
+	// PushMany 把同一帧放入多个用户的离线队列，用于大群中不在线的成员，只访问一次存储
+	PushMany(ctx context.Context, userIDs []string, frame protocol.Envelope) error
+	// Drain 取出并清空该用户的离线帧
+	Drain(ctx context.Context, userID string) ([]protocol.Envelope, error)
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+type memoryOfflineEntry struct {
+	frames    []protocol.Envelope
+	expiresAt time.Time
+}
+
+type MemoryOfflineQueue struct {
+	mu      sync.Mutex
+	entries map[string]*memoryOfflineEntry
+}
+
+func NewMemoryOfflineQueue() *MemoryOfflineQueue {
+	return &MemoryOfflineQueue{entries: make(map[string]*memoryOfflineEntry)}
+}
+
+func (q *MemoryOfflineQueue) Push(ctx context.Context, userID string, frames []protocol.Envelope) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.expire(now)
+	q.push(now, userID, frames)
+	return nil
+}
+
+func (q *MemoryOfflineQueue) PushMany(ctx context.Context, userIDs []string, frame protocol.Envelope) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.expire(now)
+	frames := []protocol.Envelope{frame}
+	for _, userID := range userIDs {
+		q.push(now, userID, frames)
+	}
+	return nil
+}
+
+func (q *MemoryOfflineQueue) expire(now time.Time) {
+	for id, entry := range q.entries {
+		if now.After(entry.expiresAt) {
+			delete(q.entries, id)
+		}
+	}
+}
+
+func (q *MemoryOfflineQueue) push(now time.Time, userID string, frames []protocol.Envelope) {
+	entry, ok := q.entries[userID]
+	if !ok {
+		entry = &memoryOfflineEntry{}
+		q.entries[userID] = entry
+	}
+	entry.frames = append(entry.frames, frames...)
+	if len(entry.frames) > offlineQueueLimit {
+		entry.frames = entry.frames[len(entry.frames)-offlineQueueLimit:]
+	}
+	entry.expiresAt = now.Add(offlineQueueTTL)
+}
+
+func (q *MemoryOfflineQueue) Drain(ctx context.Context, userID string) ([]protocol.Envelope, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.entries[userID]
+	delete(q.entries, userID)
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, nil
+	}
+	return entry.frames, nil
+}
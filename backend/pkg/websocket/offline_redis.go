@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cyperlo/im/pkg/protocol"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const offlineKeyPrefix = "im:offline:"
+
+type RedisOfflineQueue struct {
+	client *goredis.Client
+}
+
+func NewRedisOfflineQueue(client *goredis.Client) *RedisOfflineQueue {
+	return &RedisOfflineQueue{client: client}
+}
+
+func (q *RedisOfflineQueue) Push(ctx context.Context, userID string, frames []protocol.Envelope) error {
+	values := make([]interface{}, 0, len(frames))
+	for _, frame := range frames {
+		data, err := json.Marshal(frame)
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+
+	key := offlineKeyPrefix + userID
+	pipe := q.client.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.LTrim(ctx, key, -offlineQueueLimit, -1)
+	pipe.Expire(ctx, key, offlineQueueTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PushMany 帧只序列化一次，所有用户的写入放在同一个 pipeline 中。不使用事务，
+// 各用户的队列互不相关，也避免集群模式下跨 slot 的 MULTI
+func (q *RedisOfflineQueue) PushMany(ctx context.Context, userIDs []string, frame protocol.Envelope) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	pipe := q.client.Pipeline()
+	for _, userID := range userIDs {
+		key := offlineKeyPrefix + userID
+		pipe.RPush(ctx, key, data)
+		pipe.LTrim(ctx, key, -offlineQueueLimit, -1)
+		pipe.Expire(ctx, key, offlineQueueTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (q *RedisOfflineQueue) Drain(ctx context.Context, userID string) ([]protocol.Envelope, error) {
+	key := offlineKeyPrefix + userID
+	pipe := q.client.TxPipeline()
+	values := pipe.LRange(ctx, key, 0, -1)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	frames := make([]protocol.Envelope, 0, len(values.Val()))
+	for _, value := range values.Val() {
+		var frame protocol.Envelope
+		if err := json.Unmarshal([]byte(value), &frame); err != nil {
+			log.Printf("Invalid offline frame of user %s: %v", userID, err)
+			continue
+		}
+		// 升级前写入的帧没有信封，无法按连接的编解码器重新编码
+		if frame.Type == "" {
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cyperlo/im/pkg/protocol"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func testOfflineQueues(t *testing.T) map[string]func(t *testing.T) OfflineQueue {
+	return map[string]func(t *testing.T) OfflineQueue{
+		"memory": func(t *testing.T) OfflineQueue {
+			return NewMemoryOfflineQueue()
+		},
+		"redis": func(t *testing.T) OfflineQueue {
+			server := miniredis.RunT(t)
+			client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return NewRedisOfflineQueue(client)
+		},
+	}
+}
+
+func testFrame(deliveryID string) protocol.Envelope {
+	env := protocol.NewEnvelope(protocol.FrameChat, protocol.ChatMessage{From: "sender", Content: deliveryID})
+	env.DeliveryID = deliveryID
+	return env
+}
+
+func deliveryIDs(frames []protocol.Envelope) []string {
+	ids := make([]string, len(frames))
+	for i, frame := range frames {
+		ids[i] = frame.DeliveryID
+	}
+	return ids
+}
+
+func TestOfflineQueue(t *testing.T) {
+	for name, newQueue := range testOfflineQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := newQueue(t)
+
+			if err := queue.Push(ctx, "alice", []protocol.Envelope{testFrame("d1"), testFrame("d2")}); err != nil {
+				t.Fatal(err)
+			}
+			if err := queue.PushMany(ctx, []string{"alice", "bob", "carol"}, testFrame("d3")); err != nil {
+				t.Fatal(err)
+			}
+			if err := queue.PushMany(ctx, nil, testFrame("d4")); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"alice": "[d1 d2 d3]", "bob": "[d3]", "carol": "[d3]", "dave": "[]"}
+			for userID, ids := range want {
+				frames, err := queue.Drain(ctx, userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := fmt.Sprint(deliveryIDs(frames)); got != ids {
+					t.Errorf("Drain(%s) = %s, want %s", userID, got, ids)
+				}
+				if len(frames) > 0 && frames[0].Type != protocol.FrameChat {
+					t.Errorf("Drain(%s) lost the frame type: %+v", userID, frames[0])
+				}
+			}
+
+			// Drain 之后队列为空
+			if frames, _ := queue.Drain(ctx, "alice"); len(frames) != 0 {
+				t.Errorf("second Drain(alice) = %v", deliveryIDs(frames))
+			}
+		})
+	}
+}
+
+func TestOfflineQueueLimit(t *testing.T) {
+	for name, newQueue := range testOfflineQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := newQueue(t)
+
+			for i := 0; i < offlineQueueLimit+5; i++ {
+				if err := queue.PushMany(ctx, []string{"alice"}, testFrame(fmt.Sprintf("d%d", i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			frames, err := queue.Drain(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(frames) != offlineQueueLimit || frames[0].DeliveryID != "d5" {
+				t.Fatalf("kept %d frames starting at %s, want the latest %d", len(frames), frames[0].DeliveryID, offlineQueueLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package websocket
+
+import (
+	"context"
+	"time"
+)
+
+// Conn 注册表中的一条连接记录，SessionID 为建立连接的登录会话
+type Conn struct {
+	UserID    string `json:"user_id"`
+	NodeID    string `json:"node_id"`
+	ConnID    string `json:"conn_id"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// Registry 记录每个用户的连接分布在哪些网关节点上。记录带 TTL，
+// 节点定期为存活的连接续期，节点宕机后其记录自动过期
+type Registry interface {
+	Register(ctx context.Context, conn Conn, ttl time.Duration) error
+	Unregister(ctx context.Context, conn Conn) error
+	Refresh(ctx context.Context, conns []Conn, ttl time.Duration) error
+	// Lookup 返回 userID -> nodeID -> 连接 ID 列表，只包含未过期的记录
+	Lookup(ctx context.Context, userIDs ...string) (map[string]map[string][]string, error)
+	// Users 返回当前有连接记录的用户
+	Users(ctx context.Context) ([]string, error)
+	// Sessions 返回该用户在任意节点上有连接的会话
+	Sessions(ctx context.Context, userID string) ([]string, error)
+}
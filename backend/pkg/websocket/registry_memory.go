@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryRegistry 进程内的连接注册表，多个 Hub 共用一个实例即可模拟多个节点
+type MemoryRegistry struct {
+	mu    sync.Mutex
+	conns map[string]map[Conn]time.Time
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{conns: make(map[string]map[Conn]time.Time)}
+}
+
+func (r *MemoryRegistry) Register(ctx context.Context, conn Conn, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(conn, time.Now().Add(ttl))
+	return nil
+}
+
+func (r *MemoryRegistry) Unregister(ctx context.Context, conn Conn) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if conns, ok := r.conns[conn.UserID]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(r.conns, conn.UserID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRegistry) Refresh(ctx context.Context, conns []Conn, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, conn := range conns {
+		r.set(conn, now.Add(ttl))
+	}
+	for userID, entries := range r.conns {
+		for conn, expiresAt := range entries {
+			if now.After(expiresAt) {
+				delete(entries, conn)
+			}
+		}
+		if len(entries) == 0 {
+			delete(r.conns, userID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRegistry) Lookup(ctx context.Context, userIDs ...string) (map[string]map[string][]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[string]map[string][]string, len(userIDs))
+	for _, userID := range userIDs {
+		nodes := make(map[string][]string)
+		for conn, expiresAt := range r.conns[userID] {
+			if now.Before(expiresAt) {
+				nodes[conn.NodeID] = append(nodes[conn.NodeID], conn.ConnID)
+			}
+		}
+		result[userID] = nodes
+	}
+	return result, nil
+}
+
+func (r *MemoryRegistry) Users(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var users []string
+	for userID, entries := range r.conns {
+		for _, expiresAt := range entries {
+			if now.Before(expiresAt) {
+				users = append(users, userID)
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+func (r *MemoryRegistry) Sessions(ctx context.Context, userID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var sessions []string
+	for conn, expiresAt := range r.conns[userID] {
+		if conn.SessionID != "" && now.Before(expiresAt) && !slices.Contains(sessions, conn.SessionID) {
+			sessions = append(sessions, conn.SessionID)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *MemoryRegistry) set(conn Conn, expiresAt time.Time) {
+	conns, ok := r.conns[conn.UserID]
+	if !ok {
+		conns = make(map[Conn]time.Time)
+		r.conns[conn.UserID] = conns
+	}
+	conns[conn] = expiresAt
+}
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 每个用户一个 ZSET，成员为 nodeID|connID|sessionID，分数为过期时间（毫秒）。
+// key 本身的过期时间随最近一次续期延长，用户全部连接过期后 key 自动删除
+const registryKeyPrefix = "im:conns:"
+
+type RedisRegistry struct {
+	client *goredis.Client
+}
+
+func NewRedisRegistry(client *goredis.Client) *RedisRegistry {
+	return &RedisRegistry{client: client}
+}
+
+func (r *RedisRegistry) Register(ctx context.Context, conn Conn, ttl time.Duration) error {
+	return r.Refresh(ctx, []Conn{conn}, ttl)
+}
+
+func (r *RedisRegistry) Unregister(ctx context.Context, conn Conn) error {
+	return r.client.ZRem(ctx, registryKeyPrefix+conn.UserID, registryMember(conn)).Err()
+}
+
+func (r *RedisRegistry) Refresh(ctx context.Context, conns []Conn, ttl time.Duration) error {
+	if len(conns) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	expiresAt := float64(now.Add(ttl).UnixMilli())
+	pipe := r.client.Pipeline()
+	for _, conn := range conns {
+		key := registryKeyPrefix + conn.UserID
+		pipe.ZAdd(ctx, key, goredis.Z{Score: expiresAt, Member: registryMember(conn)})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		pipe.PExpire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisRegistry) Lookup(ctx context.Context, userIDs ...string) (map[string]map[string][]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	pipe := r.client.Pipeline()
+	cmds := make([]*goredis.StringSliceCmd, len(userIDs))
+	for i, userID := range userIDs {
+		cmds[i] = pipe.ZRangeByScore(ctx, registryKeyPrefix+userID, &goredis.ZRangeBy{Min: "(" + now, Max: "+inf"})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string][]string, len(userIDs))
+	for i, userID := range userIDs {
+		nodes := make(map[string][]string)
+		for _, member := range cmds[i].Val() {
+			conn, ok := parseRegistryMember(member)
+			if !ok {
+				continue
+			}
+			nodes[conn.NodeID] = append(nodes[conn.NodeID], conn.ConnID)
+		}
+		result[userID] = nodes
+	}
+	return result, nil
+}
+
+func (r *RedisRegistry) Sessions(ctx context.Context, userID string) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := r.client.ZRangeByScore(ctx, registryKeyPrefix+userID, &goredis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil && err != goredis.Nil {
+		return nil, err
+	}
+
+	var sessions []string
+	for _, member := range members {
+		conn, ok := parseRegistryMember(member)
+		if ok && conn.SessionID != "" && !slices.Contains(sessions, conn.SessionID) {
+			sessions = append(sessions, conn.SessionID)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *RedisRegistry) Users(ctx context.Context) ([]string, error) {
+	var users []string
+	iter := r.client.Scan(ctx, 0, registryKeyPrefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		users = append(users, strings.TrimPrefix(iter.Val(), registryKeyPrefix))
+	}
+	return users, iter.Err()
+}
+
+func registryMember(conn Conn) string {
+	return conn.NodeID + "|" + conn.ConnID + "|" + conn.SessionID
+}
+
+// parseRegistryMember 也接受升级前写入的 nodeID|connID，这类记录不带会话，到期后自动删除
+func parseRegistryMember(member string) (Conn, bool) {
+	parts := strings.SplitN(member, "|", 3)
+	if len(parts) < 2 {
+		return Conn{}, false
+	}
+	conn := Conn{NodeID: parts[0], ConnID: parts[1]}
+	if len(parts) == 3 {
+		conn.SessionID = parts[2]
+	}
+	return conn, true
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestRegistrySessions(t *testing.T) {
+	registries := map[string]func(t *testing.T) (Registry, *goredis.Client){
+		"memory": func(t *testing.T) (Registry, *goredis.Client) {
+			return NewMemoryRegistry(), nil
+		},
+		"redis": func(t *testing.T) (Registry, *goredis.Client) {
+			server := miniredis.RunT(t)
+			client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return NewRedisRegistry(client), client
+		},
+	}
+
+	for name, newRegistry := range registries {
+		t.Run(name, func(t *testing.T) {
+			registry, client := newRegistry(t)
+			ctx := context.Background()
+
+			conns := []Conn{
+				{UserID: "alice", NodeID: "a", ConnID: "c1", SessionID: "s1"},
+				{UserID: "alice", NodeID: "b", ConnID: "c2", SessionID: "s2"},
+				{UserID: "alice", NodeID: "b", ConnID: "c3", SessionID: "s1"},
+				{UserID: "alice", NodeID: "a", ConnID: "c4"},
+				{UserID: "bob", NodeID: "a", ConnID: "c5", SessionID: "s3"},
+			}
+			for _, conn := range conns {
+				if err := registry.Register(ctx, conn, time.Minute); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if client != nil {
+				// 升级前写入的记录不带会话
+				score := float64(time.Now().Add(time.Minute).UnixMilli())
+				if err := client.ZAdd(ctx, registryKeyPrefix+"alice", goredis.Z{Score: score, Member: "b|old"}).Err(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sessions, err := registry.Sessions(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(sessions)
+			if !slices.Equal(sessions, []string{"s1", "s2"}) {
+				t.Errorf("Sessions(alice) = %v, want [s1 s2]", sessions)
+			}
+
+			nodes, err := registry.Lookup(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 2
+			if client != nil {
+				want = 3
+			}
+			if len(nodes["alice"]["a"]) != 2 || len(nodes["alice"]["b"]) != want {
+				t.Errorf("Lookup(alice) = %v", nodes["alice"])
+			}
+
+			if err := registry.Unregister(ctx, conns[1]); err != nil {
+				t.Fatal(err)
+			}
+			if sessions, _ := registry.Sessions(ctx, "alice"); !slices.Equal(sessions, []string{"s1"}) {
+				t.Errorf("Sessions(alice) after unregister = %v, want [s1]", sessions)
+			}
+		})
+	}
+}
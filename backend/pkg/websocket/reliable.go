@@ -0,0 +1,263 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+const (
+	// 重投间隔从 AckTimeout 开始逐次翻倍，最长不超过 maxAckBackoff
+	maxAckBackoff = time.Minute
+	// WritePump 检查到期未确认帧的周期
+	redeliverInterval = time.Second
+)
+
+type pendingFrame struct {
+	env      protocol.Envelope
+	data     []byte
+	attempts int
+	nextAt   time.Time
+	// 同一用户的其他设备已经确认，连接断开时不再转入离线队列
+	ackedElsewhere bool
+}
+
+// outbound 一次推送及其按编解码器缓存的编码结果，推送给多个连接时每种编码只做一次。
+// 不带投递 ID 的是临时事件，key 用于合并。不能在多个 goroutine 中同时使用
+type outbound struct {
+	env     protocol.Envelope
+	key     string
+	encoded map[string][]byte
+}
+
+func newOutbound(env protocol.Envelope) *outbound {
+	return &outbound{env: env, encoded: make(map[string][]byte, 2)}
+}
+
+func (o *outbound) reliable() bool {
+	return o.env.DeliveryID != ""
+}
+
+func (o *outbound) encode(codec protocol.Codec) ([]byte, error) {
+	if data, ok := o.encoded[codec.Name()]; ok {
+		return data, nil
+	}
+	data, err := codec.Encode(&o.env)
+	if err != nil {
+		return nil, err
+	}
+	o.encoded[codec.Name()] = data
+	return data, nil
+}
+
+// sendReliable 按连接的编解码器编码后记录为待确认并放入发送队列。
+// 队列已满时不丢弃，由 WritePump 在下一次重投时直接写出，或随连接断开转入离线队列。
+// 连接已注销或待确认的帧达到 MaxPendingPerConn 时返回 false，由调用方按离线处理，
+// 后一种情况连接以 CloseResync 断开，客户端重连后通过 sync 补齐
+func (c *Client) sendReliable(out *outbound) bool {
+	data, err := out.encode(c.codec())
+	if err != nil {
+		log.Printf("Failed to encode frame %s for conn %s: %v", out.env.DeliveryID, c.ID, err)
+		return false
+	}
+
+	c.pendingMu.Lock()
+	if len(c.pending) >= config.MaxPendingPerConn {
+		c.pendingMu.Unlock()
+		c.disconnectSlow("too many unacked frames")
+		return false
+	}
+	if c.pending == nil {
+		c.pending = make(map[string]*pendingFrame)
+	}
+	c.pending[out.env.DeliveryID] = &pendingFrame{env: out.env, data: data, attempts: 1, nextAt: time.Now().Add(config.AckTimeout)}
+	c.pendingMu.Unlock()
+
+	if !c.enqueue(queuedFrame{data: data, deliveryID: out.env.DeliveryID}) {
+		c.pendingMu.Lock()
+		delete(c.pending, out.env.DeliveryID)
+		c.pendingMu.Unlock()
+		return false
+	}
+	return true
+}
+
+// deferRedelivery 可靠帧没能进入发送队列，交给 WritePump 在下一次重投时直接写出
+func (c *Client) deferRedelivery(deliveryID string) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if p, ok := c.pending[deliveryID]; ok {
+		p.attempts = 0
+		p.nextAt = time.Now()
+	}
+}
+
+// Ack 客户端确认收到投递，返回该投递是否仍在等待确认
+func (c *Client) Ack(deliveryID string) bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if _, ok := c.pending[deliveryID]; !ok {
+		return false
+	}
+	delete(c.pending, deliveryID)
+	return true
+}
+
+// markAckedElsewhere 同一用户的其他连接确认了这一帧。本连接仍继续重投，只是断开时不再转入离线队列
+func (c *Client) markAckedElsewhere(deliveryID string) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if p, ok := c.pending[deliveryID]; ok {
+		p.ackedElsewhere = true
+	}
+}
+
+// Ack 记录连接对投递的确认，并通知该用户在本节点上的其他连接：
+// 这一帧已有设备收到，其他设备断开后通过 sync 补齐，不必再放入离线队列推给所有设备
+func (h *Hub) Ack(client *Client, deliveryID string) bool {
+	if !client.Ack(deliveryID) {
+		return false
+	}
+	for _, other := range h.shard(client.UserID).clients(client.UserID) {
+		if other != client {
+			other.markAckedElsewhere(deliveryID)
+		}
+	}
+	return true
+}
+
+// dueFrames 返回到期需要重投的帧，以及超过重投次数上限、应转入离线队列的帧
+func (c *Client) dueFrames(now time.Time) (resend [][]byte, expired []protocol.Envelope) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	for id, p := range c.pending {
+		if now.Before(p.nextAt) {
+			continue
+		}
+		if p.attempts > config.MaxRedeliveries {
+			expired = append(expired, p.env)
+			delete(c.pending, id)
+			continue
+		}
+		p.attempts++
+		p.nextAt = now.Add(ackBackoff(p.attempts))
+		resend = append(resend, p.data)
+	}
+	return resend, expired
+}
+
+// takePending 取出全部未确认的帧，连接关闭时调用。已被同一用户其他设备确认的帧不包括在内
+func (c *Client) takePending() []protocol.Envelope {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	frames := make([]protocol.Envelope, 0, len(c.pending))
+	for _, p := range c.pending {
+		if !p.ackedElsewhere {
+			frames = append(frames, p.env)
+		}
+	}
+	c.pending = nil
+	return frames
+}
+
+func (c *Client) write(payload []byte) error {
+	c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+	return c.Conn.WriteMessage(c.messageType(), payload)
+}
+
+func ackBackoff(attempts int) time.Duration {
+	backoff := config.AckTimeout
+	for i := 1; i < attempts && backoff < maxAckBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxAckBackoff)
+}
+
+// redeliver 由 WritePump 定时调用，重写到期未确认的帧；超过重投上限的帧转入离线队列
+func (c *Client) redeliver(h *Hub) error {
+	resend, expired := c.dueFrames(time.Now())
+	for _, payload := range resend {
+		if err := c.write(payload); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		log.Printf("Conn %s did not ack %d frames, moving them to offline queue", c.ID, len(expired))
+		h.enqueueOffline(c.UserID, expired)
+	}
+	return nil
+}
+
+// enqueueOffline 把帧放入用户的离线队列，用户下次连接时重新投递
+func (h *Hub) enqueueOffline(userID string, frames []protocol.Envelope) {
+	h.mu.RLock()
+	queue := h.offline
+	h.mu.RUnlock()
+	if queue == nil || len(frames) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := queue.Push(ctx, userID, frames); err != nil {
+		log.Printf("Failed to queue %d offline frames for user %s: %v", len(frames), userID, err)
+	}
+}
+
+// enqueueOfflineMany 把同一帧放入多个用户的离线队列，只访问一次存储
+func (h *Hub) enqueueOfflineMany(userIDs []string, frame protocol.Envelope) {
+	h.mu.RLock()
+	queue := h.offline
+	h.mu.RUnlock()
+	if queue == nil || len(userIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := queue.PushMany(ctx, userIDs, frame); err != nil {
+		log.Printf("Failed to queue offline frame %s for %d users: %v", frame.DeliveryID, len(userIDs), err)
+	}
+}
+
+// drainOffline 取出离线队列中的帧，推送给该用户在本节点上的每一个连接，而不只是刚建立的这一个，
+// 同时在线的其他设备同样需要这些帧（客户端按投递 ID 去重）
+func (h *Hub) drainOffline(client *Client) {
+	h.mu.RLock()
+	queue := h.offline
+	h.mu.RUnlock()
+	if queue == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	frames, err := queue.Drain(ctx, client.UserID)
+	if err != nil {
+		log.Printf("Failed to drain offline queue of user %s: %v", client.UserID, err)
+		return
+	}
+	if len(frames) == 0 {
+		return
+	}
+
+	userIDs := []string{client.UserID}
+	var lost []protocol.Envelope
+	conns := 0
+	for _, frame := range frames {
+		local, _ := h.deliverLocal(userIDs, newOutbound(frame))
+		if local[0] == 0 {
+			lost = append(lost, frame)
+		}
+		conns = max(conns, local[0])
+	}
+	if len(lost) > 0 {
+		// 用户的连接在取出离线消息期间全部关闭，放回队列
+		h.enqueueOffline(client.UserID, lost)
+	}
+	log.Printf("Delivered %d offline frames to %d conns of user %s", len(frames)-len(lost), conns, client.UserID)
+}
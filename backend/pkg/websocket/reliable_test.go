@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cyperlo/im/pkg/protocol"
+)
+
+// 待确认的帧达到上限后不再记录，连接以 CloseResync 断开，由调用方按离线处理
+func TestSendReliableLimitsPendingFrames(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{MaxPendingPerConn: 2})
+
+	conn, peer := newTestConn(t)
+	client := NewClient("conn", "alice", "", conn, nil)
+	before := Stats()
+
+	for _, id := range []string{"d1", "d2"} {
+		if !client.sendReliable(newOutbound(testFrame(id))) {
+			t.Fatalf("sendReliable(%s) = false", id)
+		}
+	}
+	if client.sendReliable(newOutbound(testFrame("d3"))) {
+		t.Fatal("sendReliable over the pending limit = true")
+	}
+	expectClose(t, peer, CloseResync)
+
+	if got := Stats().SlowDisconnects - before.SlowDisconnects; got != 1 {
+		t.Errorf("SlowDisconnects delta = %d, want 1", got)
+	}
+	if pending := client.takePending(); len(pending) != 2 {
+		t.Errorf("%d frames pending, want 2", len(pending))
+	}
+}
+
+// 一个设备确认后，另一个设备断开时不再把这一帧放入离线队列
+func TestUnregisterSkipsFramesAckedByOtherDevice(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{})
+
+	hub := NewHub()
+	offline := NewMemoryOfflineQueue()
+	hub.UseOfflineQueue(offline)
+	phone := NewClient("phone", "alice", "", nil, protocol.JSON)
+	laptop := NewClient("laptop", "alice", "", nil, protocol.Proto)
+	hub.RegisterClient(phone)
+	hub.RegisterClient(laptop)
+
+	hub.deliverLocal([]string{"alice"}, newOutbound(testFrame("d1")))
+	hub.deliverLocal([]string{"alice"}, newOutbound(testFrame("d2")))
+	if !hub.Ack(phone, "d1") {
+		t.Fatal("phone was not waiting for d1")
+	}
+	if hub.Ack(phone, "d1") {
+		t.Error("second ack of d1 = true")
+	}
+
+	hub.UnregisterClient(laptop)
+	frames, err := offline.Drain(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := deliveryIDs(frames); len(ids) != 1 || ids[0] != "d2" {
+		t.Fatalf("offline frames = %v, want [d2]", ids)
+	}
+}
@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// SlowConsumerPolicy 连接的发送队列写满后的处理方式。聊天等需要确认的帧不会被丢弃：
+// 队列满时改由 WritePump 在下一次重投时直接写出，连接断开后转入离线队列
+type SlowConsumerPolicy string
+
+const (
+	// PolicyDropOldest 丢弃队列中最早的临时帧（如输入状态、回复），没有临时帧时丢弃新的临时帧
+	PolicyDropOldest SlowConsumerPolicy = "drop_oldest"
+	// PolicyCoalesce 同一个 key 的临时事件只保留最新一条，队列仍满时按 drop_oldest 处理
+	PolicyCoalesce SlowConsumerPolicy = "coalesce"
+	// PolicyDisconnect 以 CloseResync 断开连接，客户端重连后通过 sync 补齐
+	PolicyDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+func parseSlowConsumerPolicy(policy SlowConsumerPolicy) (SlowConsumerPolicy, error) {
+	switch policy {
+	case "":
+		return PolicyCoalesce, nil
+	case PolicyDropOldest, PolicyCoalesce, PolicyDisconnect:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown websocket slow consumer policy %q", policy)
+	}
+}
+
+// SendStats 本节点发送队列的累计计数
+type SendStats struct {
+	// DroppedEphemeral 因队列已满被丢弃的临时帧
+	DroppedEphemeral int64 `json:"dropped_ephemeral"`
+	// Coalesced 被同 key 的新事件替换的临时帧
+	Coalesced int64 `json:"coalesced"`
+	// Deferred 因队列已满改为重投的可靠帧
+	Deferred int64 `json:"deferred"`
+	// SlowDisconnects 按 disconnect 策略断开的连接
+	SlowDisconnects int64 `json:"slow_disconnects"`
+}
+
+var sendStats struct {
+	droppedEphemeral atomic.Int64
+	coalesced        atomic.Int64
+	deferred         atomic.Int64
+	slowDisconnects  atomic.Int64
+}
+
+// Stats 返回进程启动以来的发送队列计数
+func Stats() SendStats {
+	return SendStats{
+		DroppedEphemeral: sendStats.droppedEphemeral.Load(),
+		Coalesced:        sendStats.coalesced.Load(),
+		Deferred:         sendStats.deferred.Load(),
+		SlowDisconnects:  sendStats.slowDisconnects.Load(),
+	}
+}
+
+// queuedFrame 已按连接的编解码器编码的一帧。可靠帧带投递 ID，临时帧可以带合并用的 key
+type queuedFrame struct {
+	data       []byte
+	deliveryID string
+	key        string
+}
+
+func (f *queuedFrame) reliable() bool {
+	return f.deliveryID != ""
+}
+
+type pushResult int
+
+const (
+	pushQueued pushResult = iota
+	pushCoalesced
+	pushEvicted
+	pushDropped
+	pushDeferred
+	pushOverflow
+	pushClosed
+)
+
+// sendQueue 连接的有界发送队列，写入不会阻塞，由 WritePump 通过 notify 得知有新数据
+type sendQueue struct {
+	mu     sync.Mutex
+	frames []queuedFrame
+	closed bool
+	notify chan struct{}
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{notify: make(chan struct{}, 1)}
+}
+
+// push 按策略放入一帧。pushEvicted 表示丢弃了队列中最早的临时帧后入队；
+// pushDropped、pushDeferred、pushOverflow 表示新帧没有入队
+func (q *sendQueue) push(frame queuedFrame, limit int, policy SlowConsumerPolicy) pushResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return pushClosed
+	}
+
+	if policy == PolicyCoalesce && frame.key != "" {
+		for i := range q.frames {
+			if q.frames[i].key == frame.key {
+				q.frames[i] = frame
+				return pushCoalesced
+			}
+		}
+	}
+
+	result := pushQueued
+	if len(q.frames) >= limit {
+		if policy == PolicyDisconnect {
+			return pushOverflow
+		}
+		victim := -1
+		for i := range q.frames {
+			if !q.frames[i].reliable() {
+				victim = i
+				break
+			}
+		}
+		if victim < 0 {
+			if frame.reliable() {
+				return pushDeferred
+			}
+			return pushDropped
+		}
+		q.frames = append(q.frames[:victim], q.frames[victim+1:]...)
+		result = pushEvicted
+	}
+
+	q.frames = append(q.frames, frame)
+	q.signal()
+	return result
+}
+
+// enqueue 按慢消费者策略放入发送队列。没能入队的可靠帧仍在等待确认，改由 WritePump 重投，
+// 连接断开后转入离线队列，这种情况同样返回 true；临时帧被丢弃或连接已注销时返回 false
+func (c *Client) enqueue(frame queuedFrame) bool {
+	switch c.queue.push(frame, config.SendBuffer, config.SlowConsumerPolicy) {
+	case pushQueued:
+		return true
+	case pushCoalesced:
+		sendStats.coalesced.Add(1)
+		return true
+	case pushEvicted:
+		sendStats.droppedEphemeral.Add(1)
+		return true
+	case pushDropped:
+		sendStats.droppedEphemeral.Add(1)
+		return false
+	case pushDeferred:
+		sendStats.deferred.Add(1)
+		log.Printf("Send queue of conn %s full, frame %s will be retried", c.ID, frame.deliveryID)
+		c.deferRedelivery(frame.deliveryID)
+		return true
+	case pushOverflow:
+		c.disconnectSlow("send queue full")
+		if frame.reliable() {
+			return true
+		}
+		sendStats.droppedEphemeral.Add(1)
+		return false
+	default:
+		return false
+	}
+}
+
+// disconnectSlow 断开跟不上推送速度的连接，只执行一次。关闭帧的写入可能阻塞，不在调用方的锁内进行
+func (c *Client) disconnectSlow(reason string) {
+	c.slowOnce.Do(func() {
+		sendStats.slowDisconnects.Add(1)
+		log.Printf("Conn %s (user %s): %s, disconnecting slow consumer", c.ID, c.UserID, reason)
+		go c.Close(CloseResync, "slow consumer, resync required")
+	})
+}
+
+// take 取出队列中的全部帧
+func (q *sendQueue) take() ([]queuedFrame, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	frames := q.frames
+	q.frames = nil
+	return frames, q.closed
+}
+
+// close 之后的写入一律失败，WritePump 写完剩余的帧后关闭连接
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.signal()
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,304 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+func reliableFrame(id string) queuedFrame {
+	return queuedFrame{data: []byte(id), deliveryID: id}
+}
+
+func ephemeralFrame(data, key string) queuedFrame {
+	return queuedFrame{data: []byte(data), key: key}
+}
+
+func frameData(frames []queuedFrame) string {
+	data := make([]string, len(frames))
+	for i, frame := range frames {
+		data[i] = string(frame.data)
+	}
+	return strings.Join(data, ",")
+}
+
+func TestSendQueuePush(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SlowConsumerPolicy
+		initial []queuedFrame
+		closed  bool
+		push    queuedFrame
+		want    pushResult
+		queued  string
+	}{
+		{
+			name:   "room left",
+			policy: PolicyDropOldest,
+			push:   reliableFrame("r1"),
+			want:   pushQueued,
+			queued: "r1",
+		},
+		{
+			name:    "full of reliable frames defers reliable frame",
+			policy:  PolicyDropOldest,
+			initial: []queuedFrame{reliableFrame("r1"), reliableFrame("r2")},
+			push:    reliableFrame("r3"),
+			want:    pushDeferred,
+			queued:  "r1,r2",
+		},
+		{
+			name:    "full of reliable frames drops ephemeral frame",
+			policy:  PolicyCoalesce,
+			initial: []queuedFrame{reliableFrame("r1"), reliableFrame("r2")},
+			push:    ephemeralFrame("e1", "k1"),
+			want:    pushDropped,
+			queued:  "r1,r2",
+		},
+		{
+			name:    "drop oldest evicts the oldest ephemeral frame",
+			policy:  PolicyDropOldest,
+			initial: []queuedFrame{reliableFrame("r1"), ephemeralFrame("e1", ""), ephemeralFrame("e2", "")},
+			push:    reliableFrame("r2"),
+			want:    pushEvicted,
+			queued:  "r1,e2,r2",
+		},
+		{
+			name:    "drop oldest ignores keys",
+			policy:  PolicyDropOldest,
+			initial: []queuedFrame{ephemeralFrame("e1", "k1"), reliableFrame("r1")},
+			push:    ephemeralFrame("e2", "k1"),
+			want:    pushEvicted,
+			queued:  "r1,e2",
+		},
+		{
+			name:    "coalesce replaces frame with same key in place",
+			policy:  PolicyCoalesce,
+			initial: []queuedFrame{ephemeralFrame("e1", "k1"), reliableFrame("r1")},
+			push:    ephemeralFrame("e2", "k1"),
+			want:    pushCoalesced,
+			queued:  "e2,r1",
+		},
+		{
+			name:    "coalesce replaces even when not full",
+			policy:  PolicyCoalesce,
+			initial: []queuedFrame{ephemeralFrame("e1", "k1")},
+			push:    ephemeralFrame("e2", "k1"),
+			want:    pushCoalesced,
+			queued:  "e2",
+		},
+		{
+			name:    "coalesce without matching key evicts oldest ephemeral",
+			policy:  PolicyCoalesce,
+			initial: []queuedFrame{ephemeralFrame("e1", "k1"), ephemeralFrame("e2", "k2")},
+			push:    ephemeralFrame("e3", "k3"),
+			want:    pushEvicted,
+			queued:  "e2,e3",
+		},
+		{
+			name:    "disconnect overflows",
+			policy:  PolicyDisconnect,
+			initial: []queuedFrame{ephemeralFrame("e1", ""), reliableFrame("r1")},
+			push:    reliableFrame("r2"),
+			want:    pushOverflow,
+			queued:  "e1,r1",
+		},
+		{
+			name:    "disconnect does not coalesce",
+			policy:  PolicyDisconnect,
+			initial: []queuedFrame{ephemeralFrame("e1", "k1"), reliableFrame("r1")},
+			push:    ephemeralFrame("e2", "k1"),
+			want:    pushOverflow,
+			queued:  "e1,r1",
+		},
+		{
+			name:   "push after close",
+			policy: PolicyCoalesce,
+			closed: true,
+			push:   reliableFrame("r1"),
+			want:   pushClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSendQueue()
+			q.frames = append(q.frames, tt.initial...)
+			if tt.closed {
+				q.close()
+			}
+			// 清掉 close 产生的通知，只检查 push 自己的通知
+			select {
+			case <-q.notify:
+			default:
+			}
+
+			if got := q.push(tt.push, 2, tt.policy); got != tt.want {
+				t.Fatalf("push() = %d, want %d", got, tt.want)
+			}
+
+			frames, closed := q.take()
+			if closed != tt.closed {
+				t.Errorf("closed = %v, want %v", closed, tt.closed)
+			}
+			if got := frameData(frames); got != tt.queued {
+				t.Errorf("queue = [%s], want [%s]", got, tt.queued)
+			}
+
+			notified := len(q.notify) > 0
+			if wantNotify := tt.want == pushQueued || tt.want == pushEvicted; notified != wantNotify {
+				t.Errorf("notified = %v, want %v", notified, wantNotify)
+			}
+		})
+	}
+}
+
+func TestClientEnqueueStats(t *testing.T) {
+	quietLog(t)
+
+	tests := []struct {
+		name   string
+		policy SlowConsumerPolicy
+		frames []queuedFrame
+		want   []bool
+		stats  SendStats
+	}{
+		{
+			name:   "drop oldest",
+			policy: PolicyDropOldest,
+			frames: []queuedFrame{ephemeralFrame("e1", ""), ephemeralFrame("e2", ""), reliableFrame("r1")},
+			want:   []bool{true, true, true},
+			stats:  SendStats{DroppedEphemeral: 1},
+		},
+		{
+			name:   "coalesce",
+			policy: PolicyCoalesce,
+			frames: []queuedFrame{ephemeralFrame("e1", "k"), ephemeralFrame("e2", "k"), reliableFrame("r1")},
+			want:   []bool{true, true, true},
+			stats:  SendStats{Coalesced: 1},
+		},
+		{
+			name:   "reliable frames deferred",
+			policy: PolicyCoalesce,
+			frames: []queuedFrame{reliableFrame("r1"), reliableFrame("r2"), reliableFrame("r3"), ephemeralFrame("e1", "")},
+			want:   []bool{true, true, true, false},
+			stats:  SendStats{Deferred: 1, DroppedEphemeral: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, Config{SendBuffer: 2, SlowConsumerPolicy: tt.policy})
+			client := NewClient("conn", "alice", "", nil, nil)
+
+			before := Stats()
+			for i, frame := range tt.frames {
+				if got := client.enqueue(frame); got != tt.want[i] {
+					t.Errorf("enqueue(%s) = %v, want %v", frame.data, got, tt.want[i])
+				}
+			}
+
+			after := Stats()
+			got := SendStats{
+				DroppedEphemeral: after.DroppedEphemeral - before.DroppedEphemeral,
+				Coalesced:        after.Coalesced - before.Coalesced,
+				Deferred:         after.Deferred - before.Deferred,
+				SlowDisconnects:  after.SlowDisconnects - before.SlowDisconnects,
+			}
+			if got != tt.stats {
+				t.Errorf("stats delta = %+v, want %+v", got, tt.stats)
+			}
+		})
+	}
+}
+
+// 延后的可靠帧在下一次重投时立即到期
+func TestClientEnqueueDefersRedelivery(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{SendBuffer: 1, SlowConsumerPolicy: PolicyDropOldest})
+
+	client := NewClient("conn", "alice", "", nil, nil)
+	out := newOutbound(testFrame("d1"))
+	if !client.sendReliable(out) {
+		t.Fatal("first sendReliable failed")
+	}
+	out = newOutbound(testFrame("d2"))
+	if !client.sendReliable(out) {
+		t.Fatal("deferred sendReliable returned false")
+	}
+
+	resend, expired := client.dueFrames(time.Now())
+	if len(resend) != 1 || len(expired) != 0 {
+		t.Fatalf("dueFrames() = %d resend, %d expired, want the deferred frame only", len(resend), len(expired))
+	}
+}
+
+// newTestConn 建立一对真实的 WebSocket 连接，返回服务端一侧和客户端一侧
+func newTestConn(t *testing.T) (*ws.Conn, *ws.Conn) {
+	t.Helper()
+	upgrader := ws.Upgrader{}
+	serverConn := make(chan *ws.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serverConn <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	return <-serverConn, peer
+}
+
+func expectClose(t *testing.T, peer *ws.Conn, code int) {
+	t.Helper()
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := peer.ReadMessage(); !ws.IsCloseError(err, code) {
+		t.Fatalf("peer read error = %v, want close %d", err, code)
+	}
+}
+
+// disconnect 策略以 CloseResync 断开连接，只断开一次，没入队的可靠帧仍等待确认
+func TestClientEnqueueDisconnectsSlowConsumer(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{SendBuffer: 1, SlowConsumerPolicy: PolicyDisconnect})
+
+	conn, peer := newTestConn(t)
+	client := NewClient("conn", "alice", "", conn, nil)
+	before := Stats()
+
+	if !client.sendReliable(newOutbound(testFrame("d1"))) {
+		t.Fatal("first sendReliable failed")
+	}
+	for _, id := range []string{"d2", "d3"} {
+		if !client.sendReliable(newOutbound(testFrame(id))) {
+			t.Errorf("sendReliable(%s) = false, want the frame kept for redelivery", id)
+		}
+	}
+	if client.enqueue(ephemeralFrame("e1", "")) {
+		t.Error("ephemeral enqueue on a full queue = true")
+	}
+
+	expectClose(t, peer, CloseResync)
+
+	after := Stats()
+	if got := after.SlowDisconnects - before.SlowDisconnects; got != 1 {
+		t.Errorf("SlowDisconnects delta = %d, want 1", got)
+	}
+	if got := after.DroppedEphemeral - before.DroppedEphemeral; got != 1 {
+		t.Errorf("DroppedEphemeral delta = %d, want 1", got)
+	}
+	if pending := client.takePending(); len(pending) != 3 {
+		t.Errorf("%d frames pending, want 3 to be moved offline on unregister", len(pending))
+	}
+}
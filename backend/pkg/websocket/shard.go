@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// hubShardCount 连接按用户 ID 分片，同一用户的全部连接在同一个分片中
+const hubShardCount = 256
+
+// hubShard 保存一部分用户的连接。注册和注销在 mu 下复制出新的快照再整体替换，
+// 推送只读取快照，不加锁，大群扇出不会与连接的建立和断开互相阻塞
+type hubShard struct {
+	mu    sync.Mutex
+	users atomic.Pointer[map[string][]*Client]
+}
+
+// clients 返回该用户的连接快照，调用方不能修改
+func (s *hubShard) clients(userID string) []*Client {
+	if users := s.users.Load(); users != nil {
+		return (*users)[userID]
+	}
+	return nil
+}
+
+// snapshot 返回整个分片的快照，调用方不能修改
+func (s *hubShard) snapshot() map[string][]*Client {
+	if users := s.users.Load(); users != nil {
+		return *users
+	}
+	return nil
+}
+
+// add 返回加入后该用户在本分片中的连接数
+func (s *hubShard) add(client *Client) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.snapshot()
+	users := make(map[string][]*Client, len(old)+1)
+	for userID, conns := range old {
+		users[userID] = conns
+	}
+	conns := append(slices.Clip(old[client.UserID]), client)
+	users[client.UserID] = conns
+	s.users.Store(&users)
+	return len(conns)
+}
+
+// remove 连接不在分片中时返回 false
+func (s *hubShard) remove(client *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.snapshot()
+	i := slices.Index(old[client.UserID], client)
+	if i < 0 {
+		return false
+	}
+
+	users := make(map[string][]*Client, len(old))
+	for userID, conns := range old {
+		users[userID] = conns
+	}
+	conns := slices.Delete(slices.Clone(old[client.UserID]), i, i+1)
+	if len(conns) == 0 {
+		delete(users, client.UserID)
+	} else {
+		users[client.UserID] = conns
+	}
+	s.users.Store(&users)
+	return true
+}
+
+func (h *Hub) shard(userID string) *hubShard {
+	// FNV-1a
+	hash := uint32(2166136261)
+	for i := 0; i < len(userID); i++ {
+		hash ^= uint32(userID[i])
+		hash *= 16777619
+	}
+	return &h.shards[hash%hubShardCount]
+}
+
+// registered 判断该连接是否仍在 hub 中
+func (h *Hub) registered(client *Client) bool {
+	return slices.Contains(h.shard(client.UserID).clients(client.UserID), client)
+}
+
+// eachClient 遍历本节点的全部连接，fn 返回 false 时停止
+func (h *Hub) eachClient(fn func(client *Client) bool) {
+	for i := range h.shards {
+		for _, conns := range h.shards[i].snapshot() {
+			for _, client := range conns {
+				if !fn(client) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestHubShardAddRemove(t *testing.T) {
+	var shard hubShard
+	a1 := NewClient("a1", "alice", "", nil, nil)
+	a2 := NewClient("a2", "alice", "", nil, nil)
+	b1 := NewClient("b1", "bob", "", nil, nil)
+
+	if got := shard.add(a1); got != 1 {
+		t.Fatalf("add(a1) = %d, want 1", got)
+	}
+	if got := shard.add(a2); got != 2 {
+		t.Fatalf("add(a2) = %d, want 2", got)
+	}
+	shard.add(b1)
+
+	if got := shard.clients("alice"); !slices.Equal(got, []*Client{a1, a2}) {
+		t.Fatalf("clients(alice) = %v", got)
+	}
+
+	if !shard.remove(a1) {
+		t.Fatal("remove(a1) = false")
+	}
+	if shard.remove(a1) {
+		t.Fatal("second remove(a1) = true")
+	}
+	if got := shard.clients("alice"); !slices.Equal(got, []*Client{a2}) {
+		t.Fatalf("clients(alice) after remove = %v", got)
+	}
+
+	shard.remove(a2)
+	if _, ok := shard.snapshot()["alice"]; ok {
+		t.Fatal("user without connections still in snapshot")
+	}
+	if got := shard.clients("bob"); !slices.Equal(got, []*Client{b1}) {
+		t.Fatalf("clients(bob) = %v", got)
+	}
+}
+
+// 已经取出的快照在之后的注册和注销中保持不变，推送方可以不加锁遍历
+func TestHubShardSnapshotIsImmutable(t *testing.T) {
+	var shard hubShard
+	a1 := NewClient("a1", "alice", "", nil, nil)
+	a2 := NewClient("a2", "alice", "", nil, nil)
+	shard.add(a1)
+	shard.add(a2)
+
+	clients := shard.clients("alice")
+	users := shard.snapshot()
+
+	a3 := NewClient("a3", "alice", "", nil, nil)
+	shard.add(a3)
+	shard.remove(a1)
+	shard.add(NewClient("b1", "bob", "", nil, nil))
+
+	if !slices.Equal(clients, []*Client{a1, a2}) {
+		t.Errorf("clients snapshot changed to %v", clients)
+	}
+	if len(users) != 1 || !slices.Equal(users["alice"], []*Client{a1, a2}) {
+		t.Errorf("shard snapshot changed to %v", users)
+	}
+	if got := shard.clients("alice"); !slices.Equal(got, []*Client{a2, a3}) {
+		t.Errorf("clients(alice) = %v, want [a2 a3]", got)
+	}
+}
+
+// 推送与连接的建立和断开并发进行：全程在线的连接收到每一条推送，
+// 中途注销的连接只会收到注销之前的推送，且不会在注销后被写入
+func TestHubConcurrentFanOut(t *testing.T) {
+	quietLog(t)
+	withConfig(t, Config{SendBuffer: 1000})
+
+	const (
+		members = 200
+		rounds  = 50
+		churn   = 4
+	)
+	hub, userIDs, stable := newTestHub(members, 1)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < churn; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < rounds; i++ {
+				userID := userIDs[(g*rounds+i)%members]
+				client := NewClient(fmt.Sprintf("churn-%d-%d", g, i), userID, "", nil, testCodecs[i%len(testCodecs)])
+				hub.RegisterClient(client)
+				hub.UnregisterClient(client)
+				if frames, closed := client.queue.take(); !closed {
+					t.Errorf("conn %s queue still open after unregister", client.ID)
+				} else if len(frames) > rounds {
+					t.Errorf("conn %s got %d frames", client.ID, len(frames))
+				}
+				if client.queue.push(queuedFrame{data: []byte("late")}, config.SendBuffer, config.SlowConsumerPolicy) != pushClosed {
+					t.Errorf("conn %s accepted a frame after unregister", client.ID)
+				}
+			}
+		}(g)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+		for i := 0; i < rounds; i++ {
+			hub.SendToUsers(userIDs, testGroupMessage())
+		}
+	}()
+
+	close(start)
+	wg.Wait()
+
+	for _, client := range stable {
+		frames, closed := client.queue.take()
+		if closed || len(frames) != rounds {
+			t.Fatalf("stable conn %s got %d frames (closed=%v), want %d", client.ID, len(frames), closed, rounds)
+		}
+		if pending := client.takePending(); len(pending) != rounds {
+			t.Fatalf("stable conn %s has %d pending frames, want %d", client.ID, len(pending), rounds)
+		}
+	}
+	if got := hub.total.Load(); got != members {
+		t.Fatalf("hub has %d connections, want %d", got, members)
+	}
+}
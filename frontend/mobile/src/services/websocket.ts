@@ -10,6 +10,8 @@ class WebSocketService {
   private maxReconnectAttempts = 5;
   private reconnectDelay = 3000;
   private isManualClose = false;
+  // 最近收到的投递 ID，服务端重投时用于去重
+  private seenDeliveries: string[] = [];
 
   connect(token: string) {
     this.token = token;
@@ -38,6 +40,10 @@ class WebSocketService {
       console.log('WebSocket received:', JSON.stringify(message, null, 2));
       console.log('Message type:', message.type);
       console.log('=== WebSocket onmessage END ===');
+
+      if (message.delivery_id && !this.ack(message.delivery_id)) {
+        return;
+      }
       
       const state = store.getState();
       const currentUserId = state.auth.userId;
@@ -110,6 +116,19 @@ class WebSocketService {
     }, this.reconnectDelay);
   }
 
+  // 确认收到推送，返回 false 表示这是重复投递
+  private ack(deliveryId: string): boolean {
+    this.send({ type: 'ack', delivery_id: deliveryId });
+    if (this.seenDeliveries.includes(deliveryId)) {
+      return false;
+    }
+    this.seenDeliveries.push(deliveryId);
+    if (this.seenDeliveries.length > 500) {
+      this.seenDeliveries.shift();
+    }
+    return true;
+  }
+
   send(message: any) {
     if (this.ws && this.ws.readyState === WebSocket.OPEN) {
       console.log('Sending WebSocket message:', message);
@@ -7,6 +7,8 @@ class WebSocketService {
   private reconnectAttempts = 0;
   private maxReconnectAttempts = 5;
   private reconnectDelay = 3000;
+  // 最近收到的投递 ID，服务端重投时用于去重
+  private seenDeliveries: string[] = [];
 
   constructor(url: string) {
     this.url = url;
@@ -28,6 +30,10 @@ class WebSocketService {
       try {
         const message = JSON.parse(event.data);
         console.log('Received:', message);
+
+        if (message.delivery_id && !this.ack(message.delivery_id)) {
+          return;
+        }
         
         if (message.type === 'chat') {
           console.log('Dispatching chat message');
@@ -80,6 +86,19 @@ class WebSocketService {
     }
   }
 
+  // 确认收到推送，返回 false 表示这是重复投递
+  private ack(deliveryId: string): boolean {
+    this.send({ type: 'ack', delivery_id: deliveryId });
+    if (this.seenDeliveries.includes(deliveryId)) {
+      return false;
+    }
+    this.seenDeliveries.push(deliveryId);
+    if (this.seenDeliveries.length > 500) {
+      this.seenDeliveries.shift();
+    }
+    return true;
+  }
+
   send(message: any) {
     if (this.ws && this.ws.readyState === WebSocket.OPEN) {
       this.ws.send(JSON.stringify(message));